@@ -1,45 +1,255 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/yuriiwanchev/banner-rotation-service/internal/api"
+	"github.com/yuriiwanchev/banner-rotation-service/internal/cluster"
+	"github.com/yuriiwanchev/banner-rotation-service/internal/grpcserver"
+	"github.com/yuriiwanchev/banner-rotation-service/internal/kafka"
+	"github.com/yuriiwanchev/banner-rotation-service/internal/ratelimit"
 	"github.com/yuriiwanchev/banner-rotation-service/internal/repository"
 )
 
+var (
+	release   = "UNKNOWN"
+	buildDate = "UNKNOWN"
+	gitHash   = "UNKNOWN"
+)
+
 func main() {
-	dataSourceName := os.Getenv("DATABASE_URL")
-	repository.InitDB(dataSourceName)
-	repository.InitSchema()
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrate(os.Args[2:])
+		return
+	}
 
-	kafkaBrokers := os.Getenv("KAFKA_BROKERS")
-	kafkaTopic := os.Getenv("KAFKA_TOPIC")
+	api.InitBuildInfo(release, buildDate, gitHash)
 
-	api.InitKafkaProducer([]string{kafkaBrokers}, kafkaTopic)
-	api.InitRepositories()
-	api.InitRotationAlgorithm()
+	if os.Getenv("AUTH_DISABLED") == "true" {
+		log.Println("WARNING: authentication is disabled, every request is treated as admin")
+	} else {
+		api.InitAuth([]byte(os.Getenv("JWT_SECRET")), os.Getenv("ADMIN_API_KEY"))
+	}
 
 	port := ":8080"
 
 	server := &http.Server{
 		Addr:         port,
-		Handler:      http.DefaultServeMux,
+		Handler:      api.NewRouter(),
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 10 * time.Second,
 		IdleTimeout:  120 * time.Second,
 	}
 
-	http.HandleFunc("/add-banner", api.AddBannerHandler)
-	http.HandleFunc("/remove-banner", api.RemoveBannerHandler)
-	http.HandleFunc("/record-click", api.RecordClickHandler)
-	http.HandleFunc("/select-banner", api.SelectBannerHandler)
+	// The server is started before the rotation algorithm is warmed up so that
+	// liveness probes succeed while /readyz keeps traffic away.
+	go func() {
+		fmt.Printf("Starting server on %s (release %s, commit %s, built %s)...\n", port, release, gitHash, buildDate)
+		if err := server.ListenAndServe(); err != nil {
+			log.Fatalf("could not start server: %v\n", err)
+		}
+	}()
+
+	initRequestLimits()
+
+	grpcAddr := os.Getenv("GRPC_ADDR")
+	if grpcAddr == "" {
+		grpcAddr = ":9090"
+	}
+
+	go func() {
+		listener, err := net.Listen("tcp", grpcAddr)
+		if err != nil {
+			log.Fatalf("could not listen on %s: %v\n", grpcAddr, err)
+		}
+		fmt.Printf("Starting gRPC server on %s...\n", grpcAddr)
+		if err := grpcserver.NewGRPCServer().Serve(listener); err != nil {
+			log.Fatalf("could not start gRPC server: %v\n", err)
+		}
+	}()
+
+	var queryTimeout time.Duration
+	if durationEnv("DB_QUERY_TIMEOUT", &queryTimeout); queryTimeout > 0 {
+		repository.SetQueryTimeout(queryTimeout)
+	}
+
+	dataSourceName := os.Getenv("DATABASE_URL")
+	repository.InitDB(dataSourceName, dbConfig())
+	if os.Getenv("MIGRATE_ON_START") != "false" {
+		if err := repository.MigrateUp(context.Background(), repository.GetDB()); err != nil {
+			log.Fatalf("Failed to migrate database schema: %v", err)
+		}
+	}
+
+	kafkaBrokers := os.Getenv("KAFKA_BROKERS")
+	kafkaTopic := os.Getenv("KAFKA_TOPIC")
+
+	api.InitKafkaProducer([]string{kafkaBrokers}, kafkaTopic)
+
+	// The consumer is created before the warm-up so that events published by
+	// other replicas meanwhile are not lost.
+	var replicator *cluster.Replicator
+	if os.Getenv("CLUSTER_MODE") == "true" {
+		replicator = initCluster([]string{kafkaBrokers}, kafkaTopic)
+	}
+
+	// Everything selections depend on is set before the warm-up lets traffic in.
+	initFrequencyCap()
+	initImpressions()
+	api.InitRepositories()
+	api.InitRotationAlgorithm()
+
+	resyncInterval := time.Minute
+	if durationEnv("RESYNC_INTERVAL", &resyncInterval); resyncInterval > 0 {
+		api.StartResync(context.Background(), resyncInterval)
+	}
+
+	if replicator != nil {
+		go replicator.Run(context.Background())
+	}
+
+	select {}
+}
+
+// initFrequencyCap reads FREQUENCY_CAP, the views of a banner allowed per user
+// (0, the default, disables the cap), and FREQUENCY_CAP_WINDOW.
+func initFrequencyCap() {
+	limit := 0
+	window := 24 * time.Hour
+	intEnv("FREQUENCY_CAP", &limit)
+	durationEnv("FREQUENCY_CAP_WINDOW", &window)
+	if limit > 0 && window <= 0 {
+		log.Fatal("FREQUENCY_CAP_WINDOW must be positive")
+	}
+	api.InitFrequencyCap(limit, window)
+}
+
+// initImpressions reads IMPRESSION_SECRET, the key impression IDs are signed
+// with, IMPRESSION_TTL, how long clicks on an impression are counted, and
+// VIEW_CONFIRMATION_TIMEOUT, how long a view may be confirmed after the
+// selection (0, the default, counts views on selection).
+func initImpressions() {
+	key := []byte(os.Getenv("IMPRESSION_SECRET"))
+	if len(key) == 0 {
+		log.Println("IMPRESSION_SECRET is not set: impression IDs will not be accepted by other replicas " +
+			"or after a restart")
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	ttl := 24 * time.Hour
+	durationEnv("IMPRESSION_TTL", &ttl)
+	api.InitImpressions(key, ttl)
+
+	var confirmationTimeout time.Duration
+	durationEnv("VIEW_CONFIRMATION_TIMEOUT", &confirmationTimeout)
+	api.InitViewConfirmation(confirmationTimeout)
+}
+
+// initRequestLimits reads MAX_REQUEST_BODY_BYTES, RATE_LIMIT_DEFAULT ("rate:burst"
+// per second), RATE_LIMITS ("operation=rate:burst,..."), RATE_LIMIT_PER_IP and
+// TRUSTED_PROXIES.
+func initRequestLimits() {
+	var maxBodyBytes int64
+	if value := os.Getenv("MAX_REQUEST_BODY_BYTES"); value != "" {
+		var err error
+		maxBodyBytes, err = strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			log.Fatalf("invalid MAX_REQUEST_BODY_BYTES: %v\n", err)
+		}
+	}
+
+	var defaultLimit *ratelimit.Limit
+	if value := os.Getenv("RATE_LIMIT_DEFAULT"); value != "" {
+		limit, err := ratelimit.ParseLimit(value)
+		if err != nil {
+			log.Fatalf("invalid RATE_LIMIT_DEFAULT: %v\n", err)
+		}
+		defaultLimit = &limit
+	}
+
+	limits, err := ratelimit.ParseLimits(os.Getenv("RATE_LIMITS"))
+	if err != nil {
+		log.Fatalf("invalid RATE_LIMITS: %v\n", err)
+	}
+
+	api.InitRequestLimits(maxBodyBytes, defaultLimit, limits)
+
+	if value := os.Getenv("RATE_LIMIT_PER_IP"); value != "" {
+		limit, err := ratelimit.ParseLimit(value)
+		if err != nil {
+			log.Fatalf("invalid RATE_LIMIT_PER_IP: %v\n", err)
+		}
+		api.InitIPRateLimit(&limit)
+	}
+
+	proxies, err := api.ParseTrustedProxies(os.Getenv("TRUSTED_PROXIES"))
+	if err != nil {
+		log.Fatalf("invalid TRUSTED_PROXIES: %v\n", err)
+	}
+	api.InitTrustedProxies(proxies)
+}
+
+// initCluster reads INSTANCE_ID (the host name by default) and
+// CLUSTER_MAX_STALENESS.
+func initCluster(brokers []string, topic string) *cluster.Replicator {
+	id := os.Getenv("INSTANCE_ID")
+	if id == "" {
+		var err error
+		if id, err = os.Hostname(); err != nil {
+			log.Fatalf("could not determine INSTANCE_ID: %v\n", err)
+		}
+	}
+
+	maxStaleness := 5 * time.Second
+	durationEnv("CLUSTER_MAX_STALENESS", &maxStaleness)
+	if maxStaleness <= 0 {
+		log.Fatalf("invalid CLUSTER_MAX_STALENESS: %v\n", maxStaleness)
+	}
+
+	consumer := kafka.NewKafkaConsumer(brokers, topic, "banner-rotation-"+id)
+	fmt.Printf("Running in cluster mode as %q\n", id)
+	return api.InitCluster(id, consumer, maxStaleness)
+}
+
+// dbConfig reads DB_MAX_OPEN_CONNS, DB_MAX_IDLE_CONNS, DB_CONN_MAX_LIFETIME,
+// DB_CONN_MAX_IDLE_TIME and DB_CONNECT_ATTEMPTS over the defaults.
+func dbConfig() repository.DBConfig {
+	config := repository.DefaultDBConfig()
+	intEnv("DB_MAX_OPEN_CONNS", &config.MaxOpenConns)
+	intEnv("DB_MAX_IDLE_CONNS", &config.MaxIdleConns)
+	intEnv("DB_CONNECT_ATTEMPTS", &config.ConnectAttempts)
+	durationEnv("DB_CONN_MAX_LIFETIME", &config.ConnMaxLifetime)
+	durationEnv("DB_CONN_MAX_IDLE_TIME", &config.ConnMaxIdleTime)
+	return config
+}
+
+func intEnv(name string, target *int) {
+	if value := os.Getenv(name); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			log.Fatalf("invalid %s: %q\n", name, value)
+		}
+		*target = parsed
+	}
+}
 
-	fmt.Printf("Starting server on %s...\n", port)
-	if err := server.ListenAndServe(); err != nil {
-		log.Fatalf("could not start server: %v\n", err)
+func durationEnv(name string, target *time.Duration) {
+	if value := os.Getenv(name); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed < 0 {
+			log.Fatalf("invalid %s: %q\n", name, value)
+		}
+		*target = parsed
 	}
 }
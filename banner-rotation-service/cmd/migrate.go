@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/yuriiwanchev/banner-rotation-service/internal/repository"
+)
+
+const migrateUsage = "usage: banner-rotation-service migrate up | down [N] | status"
+
+// runMigrate implements the "migrate" subcommand.
+func runMigrate(args []string) {
+	if len(args) == 0 {
+		log.Fatal(migrateUsage)
+	}
+
+	repository.InitDB(os.Getenv("DATABASE_URL"), dbConfig())
+	defer repository.CloseDB()
+
+	ctx := context.Background()
+	db := repository.GetDB()
+
+	switch args[0] {
+	case "up":
+		if err := repository.MigrateUp(ctx, db); err != nil {
+			log.Fatalf("Failed to apply migrations: %v", err)
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			var err error
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				log.Fatal(migrateUsage)
+			}
+		}
+		if err := repository.MigrateDown(ctx, db, steps); err != nil {
+			log.Fatalf("Failed to roll back migrations: %v", err)
+		}
+	case "status":
+		statuses, err := repository.GetMigrationStatus(ctx, db)
+		if err != nil {
+			log.Fatalf("Failed to get migration status: %v", err)
+		}
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%s\t%s\n", status.Version, status.Name, appliedAt)
+		}
+	default:
+		log.Fatal(migrateUsage)
+	}
+}
@@ -0,0 +1,160 @@
+// Command simulate runs the rotation strategies against synthetic banners with
+// known CTRs and reports how each of them performs, without touching real
+// traffic.
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"strings"
+
+	e "github.com/yuriiwanchev/banner-rotation-service/internal/entities"
+)
+
+func main() {
+	scenarioPath := flag.String("scenario", "", "JSON file with the user groups and true CTRs of the banners")
+	rounds := flag.Int("rounds", 100000, "number of banner selections per run")
+	strategies := flag.String("strategies", "ucb1,ucb-v", "comma-separated rotation strategies")
+	explorations := flag.String("exploration", "1", "comma-separated exploration constants")
+	window := flag.Int("window", 1000, "rounds the share of optimal picks is measured over for convergence")
+	threshold := flag.Float64("threshold", 0.9, "share of optimal picks a run must keep to count as converged")
+	seed := flag.Uint64("seed", 1, "seed of the simulated traffic and clicks")
+	out := flag.String("out", "", "summary CSV file (default stdout)")
+	tracePath := flag.String("trace", "", "optional CSV file with the progress of every run")
+	every := flag.Int("every", 1000, "rounds between rows of the trace")
+	flag.Parse()
+
+	scenario := defaultScenario
+	if *scenarioPath != "" {
+		var err error
+		if scenario, err = loadScenario(*scenarioPath); err != nil {
+			log.Fatalf("Failed to load scenario: %v", err)
+		}
+	}
+	if err := scenario.validate(); err != nil {
+		log.Fatalf("Invalid scenario: %v", err)
+	}
+
+	if *rounds < 1 || *window < 1 || *every < 1 {
+		log.Fatal("rounds, window and every must be positive")
+	}
+	if *threshold <= 0 || *threshold > 1 {
+		log.Fatal("threshold must be between 0 and 1")
+	}
+	variants, err := parseVariants(*strategies, *explorations)
+	if err != nil {
+		log.Fatal(err)
+	}
+	config := Config{Rounds: *rounds, Window: *window, Threshold: *threshold, Every: *every}
+
+	summary, closeSummary := createOutput(*out)
+	defer closeSummary()
+
+	var trace *csv.Writer
+	if *tracePath != "" {
+		file, closeTrace := createOutput(*tracePath)
+		defer closeTrace()
+		trace = csv.NewWriter(file)
+		_ = trace.Write([]string{"strategy", "exploration", "round", "cumulative_regret", "optimal_share",
+			"window_optimal_share"})
+	}
+
+	results := csv.NewWriter(summary)
+	_ = results.Write([]string{"strategy", "exploration", "rounds", "cumulative_regret", "optimal_share",
+		"convergence_round"})
+
+	for _, variant := range variants {
+		// Every variant sees the same traffic, so that they are compared on
+		// equal terms.
+		rng := rand.New(rand.NewPCG(*seed, *seed))
+
+		var onCheckpoint func(Checkpoint)
+		if trace != nil {
+			onCheckpoint = func(c Checkpoint) {
+				_ = trace.Write(append(variantColumns(variant), strconv.Itoa(c.Round), formatFloat(c.Regret),
+					formatFloat(c.OptimalShare), formatFloat(c.WindowShare)))
+			}
+		}
+
+		result := Simulate(scenario, variant, config, rng, onCheckpoint)
+
+		convergedAt := ""
+		if result.ConvergedAt > 0 {
+			convergedAt = strconv.Itoa(result.ConvergedAt)
+		}
+		_ = results.Write(append(variantColumns(variant), strconv.Itoa(result.Round), formatFloat(result.Regret),
+			formatFloat(result.OptimalShare), convergedAt))
+	}
+
+	results.Flush()
+	if err := results.Error(); err != nil {
+		log.Fatalf("Failed to write summary: %v", err)
+	}
+	if trace != nil {
+		trace.Flush()
+		if err := trace.Error(); err != nil {
+			log.Fatalf("Failed to write trace: %v", err)
+		}
+	}
+}
+
+func loadScenario(path string) (Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Scenario{}, err
+	}
+	var scenario Scenario
+	if err := json.Unmarshal(data, &scenario); err != nil {
+		return Scenario{}, err
+	}
+	return scenario, nil
+}
+
+// parseVariants returns every combination of the strategies and exploration
+// constants.
+func parseVariants(strategies, explorations string) ([]Variant, error) {
+	var variants []Variant
+	for _, name := range strings.Split(strategies, ",") {
+		strategy := e.RotationStrategy(strings.TrimSpace(name))
+		switch strategy {
+		case e.StrategyUCB1, e.StrategyUCBV:
+		default:
+			return nil, fmt.Errorf("unknown strategy %q", name)
+		}
+		for _, value := range strings.Split(explorations, ",") {
+			exploration, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil || exploration <= 0 {
+				return nil, fmt.Errorf("exploration constant %q must be a positive number", value)
+			}
+			variants = append(variants, Variant{Strategy: strategy, Exploration: exploration})
+		}
+	}
+	return variants, nil
+}
+
+// createOutput opens the file at path for writing, or stdout if path is empty.
+func createOutput(path string) (io.Writer, func()) {
+	if path == "" {
+		return os.Stdout, func() {}
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		log.Fatalf("Failed to create %s: %v", path, err)
+	}
+	return file, func() { file.Close() }
+}
+
+func variantColumns(variant Variant) []string {
+	return []string{string(variant.Strategy), strconv.FormatFloat(variant.Exploration, 'f', -1, 64)}
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', 4, 64)
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+
+	e "github.com/yuriiwanchev/banner-rotation-service/internal/entities"
+	"github.com/yuriiwanchev/banner-rotation-service/internal/logic/bandit"
+)
+
+// simulatedSlot is the slot every simulation rotates banners in.
+const simulatedSlot e.SlotID = 1
+
+// Scenario is the synthetic traffic of a slot: the user groups, their share of
+// the selections and the true CTR of each banner for them.
+type Scenario struct {
+	Groups []Group `json:"groups"`
+}
+
+type Group struct {
+	ID e.UserGroupID `json:"id"`
+	// Weight is the share of selections made for the group relative to the
+	// others; 0 means 1.
+	Weight float64                `json:"weight,omitempty"`
+	CTRs   map[e.BannerID]float64 `json:"ctrs"`
+}
+
+// defaultScenario has two user groups that prefer different banners.
+var defaultScenario = Scenario{Groups: []Group{
+	{ID: 1, CTRs: map[e.BannerID]float64{1: 0.02, 2: 0.05, 3: 0.03, 4: 0.01}},
+	{ID: 2, CTRs: map[e.BannerID]float64{1: 0.06, 2: 0.02, 3: 0.04, 4: 0.01}},
+}}
+
+func (s Scenario) validate() error {
+	if len(s.Groups) == 0 {
+		return fmt.Errorf("scenario has no user groups")
+	}
+	for _, group := range s.Groups {
+		if group.Weight < 0 {
+			return fmt.Errorf("user group %d has a negative weight", group.ID)
+		}
+		if len(group.CTRs) == 0 {
+			return fmt.Errorf("user group %d has no banners", group.ID)
+		}
+		for bannerID, ctr := range group.CTRs {
+			if ctr < 0 || ctr > 1 {
+				return fmt.Errorf("CTR of banner %d for user group %d is not between 0 and 1", bannerID, group.ID)
+			}
+		}
+	}
+	return nil
+}
+
+// banners returns every banner of the scenario in order. A banner missing
+// from the CTRs of a group is never clicked by it.
+func (s Scenario) banners() []e.BannerID {
+	var banners []e.BannerID
+	for _, group := range s.Groups {
+		for bannerID := range group.CTRs {
+			if !slices.Contains(banners, bannerID) {
+				banners = append(banners, bannerID)
+			}
+		}
+	}
+	slices.Sort(banners)
+	return banners
+}
+
+// Variant is a strategy with an exploration constant to simulate.
+type Variant struct {
+	Strategy    e.RotationStrategy
+	Exploration float64
+}
+
+// Config is how long a simulation runs and when it counts as converged.
+type Config struct {
+	Rounds int
+	// Window is the number of rounds the share of optimal picks is measured
+	// over for convergence, and Threshold the share it must stay above.
+	Window    int
+	Threshold float64
+	// Every is the interval between checkpoints.
+	Every int
+}
+
+// Checkpoint is the progress of a simulation after Round selections.
+type Checkpoint struct {
+	Round int
+	// Regret is the expected clicks lost to not always picking the best
+	// banner of the group.
+	Regret       float64
+	OptimalShare float64
+	// WindowShare is the share of optimal picks over the last Window rounds.
+	WindowShare float64
+}
+
+type Result struct {
+	Variant
+	Checkpoint
+	// ConvergedAt is the round from which the share of optimal picks over
+	// every window stays above the threshold, or 0 if it never does.
+	ConvergedAt int
+}
+
+// Simulate rotates the banners of the scenario with the variant and reports
+// each checkpoint to trace, if it is set.
+func Simulate(scenario Scenario, variant Variant, config Config, rng *rand.Rand,
+	trace func(Checkpoint),
+) Result {
+	slot := &bandit.Slot{
+		Banners:     make(map[e.BannerID]e.Banner),
+		GroupData:   make(map[e.UserGroupID]map[e.BannerID]*bandit.GroupStats),
+		Strategy:    variant.Strategy,
+		Exploration: variant.Exploration,
+	}
+	for _, bannerID := range scenario.banners() {
+		slot.Banners[bannerID] = e.Banner{ID: bannerID}
+	}
+	mab := bandit.NewMultiArmedBandit(map[e.SlotID]*bandit.Slot{simulatedSlot: slot})
+
+	totalWeight := 0.0
+	best := make([]float64, len(scenario.Groups))
+	for i, group := range scenario.Groups {
+		totalWeight += weightOf(group)
+		for _, ctr := range group.CTRs {
+			best[i] = max(best[i], ctr)
+		}
+	}
+
+	var progress Checkpoint
+	optimalPicks, windowPicks := 0, 0
+	window := make([]bool, config.Window)
+	lastBelow := 0
+
+	for round := 1; round <= config.Rounds; round++ {
+		i := pickGroup(scenario.Groups, totalWeight, rng)
+		group := scenario.Groups[i]
+
+		bannerID := mab.SelectBanner(simulatedSlot, group.ID)
+		ctr := group.CTRs[bannerID]
+		if rng.Float64() < ctr {
+			_ = mab.RecordClick(simulatedSlot, bannerID, group.ID)
+		}
+
+		progress.Regret += best[i] - ctr
+		optimal := ctr == best[i]
+		if optimal {
+			optimalPicks++
+		}
+		pos := (round - 1) % config.Window
+		if window[pos] {
+			windowPicks--
+		}
+		if window[pos] = optimal; optimal {
+			windowPicks++
+		}
+
+		progress.Round = round
+		progress.OptimalShare = float64(optimalPicks) / float64(round)
+		progress.WindowShare = float64(windowPicks) / float64(min(round, config.Window))
+		if round < config.Window || progress.WindowShare < config.Threshold {
+			lastBelow = round
+		}
+		if trace != nil && (round%config.Every == 0 || round == config.Rounds) {
+			trace(progress)
+		}
+	}
+
+	result := Result{Variant: variant, Checkpoint: progress}
+	if lastBelow < config.Rounds {
+		result.ConvergedAt = lastBelow + 1
+	}
+	return result
+}
+
+func weightOf(group Group) float64 {
+	if group.Weight == 0 {
+		return 1
+	}
+	return group.Weight
+}
+
+// pickGroup returns the index of a user group drawn by weight.
+func pickGroup(groups []Group, totalWeight float64, rng *rand.Rand) int {
+	r := rng.Float64() * totalWeight
+	for i, group := range groups {
+		if r -= weightOf(group); r < 0 {
+			return i
+		}
+	}
+	return len(groups) - 1
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	e "github.com/yuriiwanchev/banner-rotation-service/internal/entities"
+)
+
+func TestSimulate(t *testing.T) {
+	scenario := Scenario{Groups: []Group{
+		{ID: 1, CTRs: map[e.BannerID]float64{1: 0.1, 2: 0.5}},
+		{ID: 2, Weight: 3, CTRs: map[e.BannerID]float64{1: 0.6}},
+	}}
+	if err := scenario.validate(); err != nil {
+		t.Fatalf("Scenario is invalid: %v", err)
+	}
+	config := Config{Rounds: 5000, Window: 200, Threshold: 0.95, Every: 1000}
+
+	for _, strategy := range []e.RotationStrategy{e.StrategyUCB1, e.StrategyUCBV} {
+		var checkpoints []Checkpoint
+		result := Simulate(scenario, Variant{Strategy: strategy, Exploration: 1}, config, rand.New(rand.NewPCG(1, 1)),
+			func(c Checkpoint) { checkpoints = append(checkpoints, c) })
+
+		if len(checkpoints) != 5 || checkpoints[4] != result.Checkpoint {
+			t.Errorf("%s: expected 5 checkpoints ending with the result, got %v", strategy, checkpoints)
+		}
+		if result.Round != config.Rounds || result.Regret <= 0 {
+			t.Errorf("%s: expected regret over %d rounds, got %+v", strategy, config.Rounds, result)
+		}
+		if result.ConvergedAt < config.Window || result.OptimalShare < config.Threshold {
+			t.Errorf("%s: expected the best banner to win, got %+v", strategy, result)
+		}
+	}
+
+	invalid := Scenario{Groups: []Group{{ID: 1, CTRs: map[e.BannerID]float64{1: 1.5}}}}
+	if err := invalid.validate(); err == nil {
+		t.Error("Expected a CTR above 1 to be rejected")
+	}
+}
+
+func TestSimulateIsReproducible(t *testing.T) {
+	config := Config{Rounds: 3000, Window: 500, Threshold: 0.9, Every: 100}
+	run := func() ([]Checkpoint, Result) {
+		var checkpoints []Checkpoint
+		result := Simulate(defaultScenario, Variant{Strategy: e.StrategyUCB1, Exploration: 1}, config,
+			rand.New(rand.NewPCG(7, 7)), func(c Checkpoint) { checkpoints = append(checkpoints, c) })
+		return checkpoints, result
+	}
+
+	first, firstResult := run()
+	second, secondResult := run()
+	if !slices.Equal(first, second) || firstResult != secondResult {
+		t.Errorf("Expected the same seed to give the same run, got %+v and %+v", firstResult, secondResult)
+	}
+}
@@ -1,16 +1,21 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
-	"strconv"
+	"sync/atomic"
+	"time"
 
 	e "github.com/yuriiwanchev/banner-rotation-service/internal/entities"
 	"github.com/yuriiwanchev/banner-rotation-service/internal/kafka"
 	"github.com/yuriiwanchev/banner-rotation-service/internal/logic/bandit"
 	m "github.com/yuriiwanchev/banner-rotation-service/internal/models"
 	"github.com/yuriiwanchev/banner-rotation-service/internal/repository"
+	"github.com/yuriiwanchev/banner-rotation-service/internal/repository/apikeyrepository"
+	"github.com/yuriiwanchev/banner-rotation-service/internal/repository/bannerrepository"
 	"github.com/yuriiwanchev/banner-rotation-service/internal/repository/slotbannersrepository"
 	"github.com/yuriiwanchev/banner-rotation-service/internal/repository/slotrepository"
 	"github.com/yuriiwanchev/banner-rotation-service/internal/repository/statisticrepository"
@@ -19,60 +24,125 @@ import (
 
 var (
 	banditService         *bandit.MultiArmedBandit
-	kafkaProducer         *kafka.Producer
-	slotBannersRepository slotbannersrepository.PgSlotBannerRepository
-	statisticRepository   statisticrepository.PgStatisticRepository
-	userGroupRepository   usergrouprepository.PgUserGroupRepository
+	kafkaProducer         atomic.Pointer[kafka.Producer]
+	slotRepository        slotrepository.SlotRepository
+	bannerRepository      bannerrepository.BannerRepository
+	slotBannersRepository slotbannersrepository.SlotBannerRepository
+	statisticRepository   statisticrepository.StatisticRepository
+	userGroupRepository   usergrouprepository.UserGroupRepository
+	apiKeyRepository      apikeyrepository.APIKeyRepository
 )
 
+// Repositories are the storage dependencies of the API.
+type Repositories struct {
+	Slots       slotrepository.SlotRepository
+	Banners     bannerrepository.BannerRepository
+	SlotBanners slotbannersrepository.SlotBannerRepository
+	Statistics  statisticrepository.StatisticRepository
+	UserGroups  usergrouprepository.UserGroupRepository
+	APIKeys     apikeyrepository.APIKeyRepository
+}
+
+// InitKafkaProducer publishes the producer atomically, as /readyz may already
+// be served.
 func InitKafkaProducer(brokers []string, topic string) {
-	kafkaProducer = kafka.NewKafkaProducer(brokers, topic)
+	kafkaProducer.Store(kafka.NewKafkaProducer(brokers, topic))
 }
 
+// InitRepositories backs the API with the Postgres repositories.
 func InitRepositories() {
-	slotBannersRepository = slotbannersrepository.PgSlotBannerRepository{DB: repository.GetDB()}
-	statisticRepository = statisticrepository.PgStatisticRepository{DB: repository.GetDB()}
-	userGroupRepository = usergrouprepository.PgUserGroupRepository{DB: repository.GetDB()}
+	SetRepositories(Repositories{
+		Slots:       &slotrepository.PgSlotRepository{DB: repository.GetDB()},
+		Banners:     &bannerrepository.PgBannerRepository{DB: repository.GetDB()},
+		SlotBanners: &slotbannersrepository.PgSlotBannerRepository{DB: repository.GetDB()},
+		Statistics:  &statisticrepository.PgStatisticRepository{DB: repository.GetDB()},
+		UserGroups:  &usergrouprepository.PgUserGroupRepository{DB: repository.GetDB()},
+		APIKeys:     &apikeyrepository.PgAPIKeyRepository{DB: repository.GetDB()},
+	})
+}
+
+// SetRepositories backs the API with arbitrary repository implementations,
+// e.g. the in-memory ones in tests.
+func SetRepositories(repositories Repositories) {
+	slotRepository = repositories.Slots
+	bannerRepository = repositories.Banners
+	banners.clear()
+	slotBannersRepository = repositories.SlotBanners
+	statisticRepository = repositories.Statistics
+	userGroupRepository = repositories.UserGroups
+	apiKeyRepository = repositories.APIKeys
 }
 
 func InitRotationAlgorithm() {
-	slots := make(map[e.SlotID]*bandit.Slot)
+	slots, err := loadSlots(context.Background())
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	slotRepo := slotrepository.PgSlotRepository{DB: repository.GetDB()}
-	slotBannersRepo := slotbannersrepository.PgSlotBannerRepository{DB: repository.GetDB()}
-	statisticRepo := statisticrepository.PgStatisticRepository{DB: repository.GetDB()}
+	banditService = bandit.NewMultiArmedBandit(slots)
+	rotationReady.Store(true)
+}
+
+// loadSlots reads the rotation settings and banners of every slot, their schedules,
+// today's impressions and their statistics per user group.
+func loadSlots(ctx context.Context) (map[e.SlotID]*bandit.Slot, error) {
+	slots := make(map[e.SlotID]*bandit.Slot)
 
-	dbSlots, err := slotRepo.GetAllSlots()
+	dbSlots, err := slotRepository.GetAllSlots(ctx)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
-	for _, slot := range dbSlots {
-		banners, err := slotBannersRepo.GetBannersForSlot(slot.ID)
+	for _, dbSlot := range dbSlots {
+		slotBanners, err := slotBannersRepository.GetSlotBanners(ctx, dbSlot.ID)
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
+		}
+
+		slot := &bandit.Slot{
+			Banners:        make(map[e.BannerID]e.Banner),
+			GroupData:      make(map[e.UserGroupID]map[e.BannerID]*bandit.GroupStats),
+			Schedules:      make(map[e.BannerID]bandit.Schedule),
+			Goal:           dbSlot.Goal,
+			Strategy:       dbSlot.Strategy,
+			HoldoutPercent: dbSlot.HoldoutPercent,
+		}
+		for _, slotBanner := range slotBanners {
+			slot.Banners[slotBanner.BannerID] = e.Banner{ID: slotBanner.BannerID}
+			slot.Schedules[slotBanner.BannerID] = scheduleOf(slotBanner)
 		}
 
-		slots[slot.ID] = &bandit.Slot{
-			Banners:   make(map[e.BannerID]e.Banner),
-			GroupData: make(map[e.UserGroupID]map[e.BannerID]*bandit.GroupStats),
+		slot.DeliveryDay = bandit.Day(time.Now())
+		slot.Delivered, err = statisticRepository.GetDailyImpressionsForSlot(ctx, dbSlot.ID, slot.DeliveryDay)
+		if err != nil {
+			return nil, err
 		}
 
-		for _, banner := range banners {
-			slots[slot.ID].Banners[banner.ID] = *banner
-			stat, err := statisticRepo.GetStatisticsForSlotAndBanner(slot.ID, banner.ID)
-			if err != nil {
+		stats, err := statisticRepository.GetStatisticsForSlot(ctx, dbSlot.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, stat := range stats {
+			// Statistics outlive the removal of a banner from the slot.
+			if _, exists := slot.Banners[stat.BannerID]; !exists {
 				continue
 			}
-			slots[slot.ID].GroupData[stat.UserGroupID] = make(map[e.BannerID]*bandit.GroupStats)
-			slots[slot.ID].GroupData[stat.UserGroupID][banner.ID] = &bandit.GroupStats{
-				Views:  stat.Views,
-				Clicks: stat.Clicks,
+			if slot.GroupData[stat.UserGroupID] == nil {
+				slot.GroupData[stat.UserGroupID] = make(map[e.BannerID]*bandit.GroupStats)
+			}
+			slot.GroupData[stat.UserGroupID][stat.BannerID] = &bandit.GroupStats{
+				Views:          stat.Views,
+				Clicks:         stat.Clicks,
+				Conversions:    stat.Conversions,
+				Revenue:        stat.Revenue,
+				RevenueSquares: stat.RevenueSquares,
 			}
 		}
+
+		slots[dbSlot.ID] = slot
 	}
 
-	banditService = bandit.NewMultiArmedBandit(slots)
+	return slots, nil
 }
 
 func jsonResponse(w http.ResponseWriter, status int, data interface{}) {
@@ -84,39 +154,25 @@ func jsonResponse(w http.ResponseWriter, status int, data interface{}) {
 	}
 }
 
-func AddBannerHandler(w http.ResponseWriter, r *http.Request) {
-	var request m.AddBannerRequest
-
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid request payload"})
-		return
-	}
-
-	if request.SlotID == 0 || request.BannerID == 0 {
-		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "SlotID and BannerID are required"})
+func errorResponse(w http.ResponseWriter, err error) {
+	var requestErr *RequestError
+	if errors.As(err, &requestErr) {
+		jsonResponse(w, requestErr.Status, map[string]string{"error": requestErr.Message})
 		return
 	}
+	jsonResponse(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+}
 
-	banditService.AddBanner(request.SlotID, request.BannerID)
-
-	if err := slotBannersRepository.AddBannerToSlot(request.SlotID, request.BannerID); err != nil {
-		log.Println(err)
-		jsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to add banner to slot to db"})
-		return
-	}
+func AddBannerHandler(w http.ResponseWriter, r *http.Request) {
+	var request m.AddBannerRequest
 
-	userGroupIDs, err := userGroupRepository.GetAllUserGroupsIDs()
-	if err != nil {
-		log.Println(err)
-		jsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to get userGroupIds from db"})
+	if err := decodeJSON(w, r, &request); err != nil {
+		errorResponse(w, err)
 		return
 	}
 
-	if err := statisticRepository.CreateStartStatisticsForBannerInSlot(request.SlotID,
-		request.BannerID, userGroupIDs); err != nil {
-		log.Println(err)
-		jsonResponse(w, http.StatusInternalServerError,
-			map[string]string{"error": "Failed to CreateStartStatisticsForBannerInSlot"})
+	if err := AddBanner(r.Context(), request); err != nil {
+		errorResponse(w, err)
 		return
 	}
 
@@ -126,25 +182,13 @@ func AddBannerHandler(w http.ResponseWriter, r *http.Request) {
 func RemoveBannerHandler(w http.ResponseWriter, r *http.Request) {
 	var request m.RemoveBannerRequest
 
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid request payload"})
-		return
-	}
-
-	if request.SlotID == 0 || request.BannerID == 0 {
-		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "SlotID and BannerID are required"})
-		return
-	}
-
-	err := banditService.RemoveBanner(request.SlotID, request.BannerID)
-	if err != nil {
-		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+	if err := decodeJSON(w, r, &request); err != nil {
+		errorResponse(w, err)
 		return
 	}
 
-	if err := slotBannersRepository.RemoveBannerFromSlot(request.SlotID, request.BannerID); err != nil {
-		log.Println(err)
-		jsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to add banner to db"})
+	if err := RemoveBanner(r.Context(), request); err != nil {
+		errorResponse(w, err)
 		return
 	}
 
@@ -154,37 +198,13 @@ func RemoveBannerHandler(w http.ResponseWriter, r *http.Request) {
 func RecordClickHandler(w http.ResponseWriter, r *http.Request) {
 	var request m.RecordClickRequest
 
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid request payload"})
-		return
-	}
-
-	if request.SlotID == 0 || request.BannerID == 0 || request.UserGroupID == 0 {
-		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "SlotID, BannerID, and UserGroup are required"})
-		return
-	}
-
-	err := banditService.RecordClick(request.SlotID, request.BannerID, request.UserGroupID)
-	if err != nil {
-		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+	if err := decodeJSON(w, r, &request); err != nil {
+		errorResponse(w, err)
 		return
 	}
 
-	event := e.Event{
-		Type:        e.Click,
-		SlotID:      request.SlotID,
-		BannerID:    request.BannerID,
-		UserGroupID: request.UserGroupID,
-	}
-
-	eventBytes, _ := json.Marshal(event)
-	slotIDBytes := idToBytes(int(request.SlotID))
-	if kafkaProducer != nil {
-		kafkaProducer.PublishMessage(slotIDBytes, eventBytes)
-	}
-
-	if err := statisticRepository.IncrementClick(request.SlotID, request.BannerID, request.UserGroupID); err != nil {
-		jsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to record click"})
+	if err := RecordClick(r.Context(), request); err != nil {
+		errorResponse(w, err)
 		return
 	}
 
@@ -193,48 +213,33 @@ func RecordClickHandler(w http.ResponseWriter, r *http.Request) {
 
 func SelectBannerHandler(w http.ResponseWriter, r *http.Request) {
 	var request m.SelectBannerRequest
-	var response m.SelectBannerResponse
 
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid request payload"})
+	if err := decodeJSON(w, r, &request); err != nil {
+		errorResponse(w, err)
 		return
 	}
 
-	if request.SlotID == 0 || request.UserGroupID == 0 {
-		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "SlotID and UserGroup are required"})
+	response, err := SelectBanner(r.Context(), request)
+	if err != nil {
+		errorResponse(w, err)
 		return
 	}
 
-	response.BannerID = banditService.SelectBanner(request.SlotID, request.UserGroupID)
-	if response.BannerID == 0 {
-		jsonResponse(w, http.StatusNotFound,
-			map[string]string{"error": "No banner available for the given slot and user group"})
-		return
-	}
+	jsonResponse(w, http.StatusOK, response)
+}
 
-	event := e.Event{
-		Type:        e.View,
-		SlotID:      request.SlotID,
-		BannerID:    response.BannerID,
-		UserGroupID: request.UserGroupID,
-	}
+func RecordConversionHandler(w http.ResponseWriter, r *http.Request) {
+	var request m.RecordConversionRequest
 
-	eventBytes, _ := json.Marshal(event)
-	slotIDBytes := idToBytes(int(request.SlotID))
-	if kafkaProducer != nil {
-		kafkaProducer.PublishMessage(slotIDBytes, eventBytes)
+	if err := decodeJSON(w, r, &request); err != nil {
+		errorResponse(w, err)
+		return
 	}
 
-	if err := statisticRepository.IncrementView(request.SlotID, response.BannerID, request.UserGroupID); err != nil {
-		jsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to record view"})
+	if err := RecordConversion(r.Context(), request); err != nil {
+		errorResponse(w, err)
 		return
 	}
 
-	jsonResponse(w, http.StatusOK, response)
-}
-
-func idToBytes(id int) []byte {
-	slotIDString := strconv.Itoa(id)
-	idBytes := []byte(slotIDString)
-	return idBytes
+	jsonResponse(w, http.StatusOK, nil)
 }
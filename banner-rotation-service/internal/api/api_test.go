@@ -0,0 +1,836 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image/gif"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	e "github.com/yuriiwanchev/banner-rotation-service/internal/entities"
+	m "github.com/yuriiwanchev/banner-rotation-service/internal/models"
+	"github.com/yuriiwanchev/banner-rotation-service/internal/ratelimit"
+	"github.com/yuriiwanchev/banner-rotation-service/internal/repository"
+	"github.com/yuriiwanchev/banner-rotation-service/internal/repository/apikeyrepository"
+	"github.com/yuriiwanchev/banner-rotation-service/internal/repository/bannerrepository"
+	"github.com/yuriiwanchev/banner-rotation-service/internal/repository/slotbannersrepository"
+	"github.com/yuriiwanchev/banner-rotation-service/internal/repository/slotrepository"
+	"github.com/yuriiwanchev/banner-rotation-service/internal/repository/statisticrepository"
+	"github.com/yuriiwanchev/banner-rotation-service/internal/repository/usergrouprepository"
+)
+
+type testAPI struct {
+	router     http.Handler
+	repos      Repositories
+	statistics *statisticrepository.MemStatisticRepository
+}
+
+// setupTestAPI backs the API with fresh in-memory repositories holding slots
+// 1-3 and user groups 1-2, and warm-starts the rotation algorithm.
+func setupTestAPI(t *testing.T) *testAPI {
+	t.Helper()
+
+	ctx := context.Background()
+	slots := slotrepository.NewMemSlotRepository()
+	for i := 1; i <= 3; i++ {
+		slots.CreateSlot(ctx, &e.Slot{ID: e.SlotID(i)})
+	}
+	userGroups := usergrouprepository.NewMemUserGroupRepository()
+	for i := 1; i <= 2; i++ {
+		userGroups.CreateUserGroup(ctx, &e.UserGroup{ID: e.UserGroupID(i)})
+	}
+	statistics := statisticrepository.NewMemStatisticRepository()
+
+	repos := Repositories{
+		Slots:       slots,
+		Banners:     bannerrepository.NewMemBannerRepository(),
+		SlotBanners: slotbannersrepository.NewMemSlotBannerRepository(),
+		Statistics:  statistics,
+		UserGroups:  userGroups,
+		APIKeys:     apikeyrepository.NewMemAPIKeyRepository(),
+	}
+	SetRepositories(repos)
+	InitRotationAlgorithm()
+
+	authenticator = nil
+	InitImpressions([]byte("test"), time.Hour)
+	t.Cleanup(func() {
+		authenticator = nil
+		frequencyCapper = nil
+		impressionSigner = nil
+		pendingViews = nil
+		rotationReady.Store(false)
+	})
+
+	return &testAPI{router: NewRouter(), repos: repos, statistics: statistics}
+}
+
+func (a *testAPI) do(t *testing.T, method, path string, body interface{}, token string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var payload []byte
+	if body != nil {
+		var err error
+		payload, err = json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rr := httptest.NewRecorder()
+	a.router.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestAddSelectAndClick(t *testing.T) {
+	a := setupTestAPI(t)
+
+	rr := a.do(t, http.MethodPost, "/v1/slots/1/banners", m.AddSlotBannerRequest{BannerID: 5}, "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Add banner: expected 200, got %d: %s", rr.Code, rr.Body)
+	}
+
+	rr = a.do(t, http.MethodPost, "/v1/slots/1/selections", m.CreateSelectionRequest{UserGroupID: 2}, "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Select banner: expected 200, got %d: %s", rr.Code, rr.Body)
+	}
+	var selection m.SelectBannerResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &selection); err != nil {
+		t.Fatal(err)
+	}
+	if selection.BannerID != 5 {
+		t.Errorf("Expected banner 5 to be selected, got %d", selection.BannerID)
+	}
+
+	rr = a.do(t, http.MethodPost, "/v1/slots/1/banners/5/clicks", m.CreateClickRequest{UserGroupID: 2}, "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Record click: expected 200, got %d: %s", rr.Code, rr.Body)
+	}
+
+	stat, err := a.statistics.GetStatistics(context.Background(), 1, 5, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.Views != 1 || stat.Clicks != 1 {
+		t.Errorf("Expected 1 view and 1 click, got %d views and %d clicks", stat.Views, stat.Clicks)
+	}
+}
+
+func TestAddBannerTwice(t *testing.T) {
+	a := setupTestAPI(t)
+
+	// The deprecated aliases accept any method, as they did before /v1.
+	rr := a.do(t, http.MethodPut, "/add-banner", m.AddBannerRequest{SlotID: 1, BannerID: 1}, "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200 for PUT on a deprecated alias, got %d: %s", rr.Code, rr.Body)
+	}
+	rr = a.do(t, http.MethodPost, "/add-banner", m.AddBannerRequest{SlotID: 1, BannerID: 1}, "")
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("Expected 500 for a banner already in the slot, got %d", rr.Code)
+	}
+	if rr.Header().Get("Deprecation") != "true" {
+		t.Errorf("Expected legacy endpoint to be marked deprecated")
+	}
+}
+
+func TestRemoveBanner(t *testing.T) {
+	a := setupTestAPI(t)
+
+	a.do(t, http.MethodPost, "/v1/slots/2/banners", m.AddSlotBannerRequest{BannerID: 3}, "")
+	a.do(t, http.MethodPost, "/v1/slots/2/banners/3/clicks", m.CreateClickRequest{UserGroupID: 1}, "")
+
+	rr := a.do(t, http.MethodDelete, "/v1/slots/2/banners/3", nil, "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body)
+	}
+
+	banners, _ := a.repos.SlotBanners.GetBannersForSlot(context.Background(), 2)
+	if len(banners) != 0 {
+		t.Errorf("Expected banner to be removed from the repository, got %v", banners)
+	}
+
+	rr = a.do(t, http.MethodPost, "/v1/slots/2/selections", m.CreateSelectionRequest{UserGroupID: 1}, "")
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a slot without banners, got %d", rr.Code)
+	}
+
+	rr = a.do(t, http.MethodDelete, "/v1/slots/100/banners/3", nil, "")
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown slot, got %d", rr.Code)
+	}
+
+	rr = a.do(t, http.MethodPost, "/v1/slots/2/banners", m.AddSlotBannerRequest{BannerID: 3}, "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200 when re-adding the banner, got %d: %s", rr.Code, rr.Body)
+	}
+	stat, err := a.statistics.GetStatistics(context.Background(), 2, 3, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.Clicks != 1 {
+		t.Errorf("Expected the re-added banner to keep its click, got %+v", stat)
+	}
+}
+
+func TestValidation(t *testing.T) {
+	a := setupTestAPI(t)
+
+	tests := []struct {
+		method string
+		path   string
+		body   interface{}
+		status int
+	}{
+		{http.MethodPost, "/v1/slots/0/selections", m.CreateSelectionRequest{UserGroupID: 1}, http.StatusBadRequest},
+		{http.MethodPost, "/v1/slots/abc/selections", m.CreateSelectionRequest{UserGroupID: 1}, http.StatusBadRequest},
+		{http.MethodPost, "/v1/slots/1/selections", m.CreateSelectionRequest{}, http.StatusBadRequest},
+		{http.MethodPost, "/select-banner", map[string]int{"slotId": 1, "userGroup": 1}, http.StatusBadRequest},
+		{http.MethodGet, "/v1/slots/1/selections", nil, http.StatusMethodNotAllowed},
+		// Deprecated aliases accept any method, so this fails on the missing body instead.
+		{http.MethodGet, "/select-banner", nil, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		if rr := a.do(t, tt.method, tt.path, tt.body, ""); rr.Code != tt.status {
+			t.Errorf("%s %s: expected %d, got %d", tt.method, tt.path, tt.status, rr.Code)
+		}
+	}
+}
+
+func TestNotReady(t *testing.T) {
+	a := setupTestAPI(t)
+	rotationReady.Store(false)
+
+	rr := a.do(t, http.MethodPost, "/v1/slots/1/selections", m.CreateSelectionRequest{UserGroupID: 1}, "")
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 before warm-up, got %d", rr.Code)
+	}
+
+	if rr := a.do(t, http.MethodGet, "/healthz", nil, ""); rr.Code != http.StatusOK {
+		t.Errorf("Expected /healthz to be served before warm-up, got %d", rr.Code)
+	}
+}
+
+func TestRoles(t *testing.T) {
+	a := setupTestAPI(t)
+	InitAuth(nil, "admin-key")
+
+	rr := a.do(t, http.MethodPost, "/v1/slots/1/selections", m.CreateSelectionRequest{UserGroupID: 1}, "")
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without credentials, got %d", rr.Code)
+	}
+
+	rr = a.do(t, http.MethodPost, "/v1/admin/api-keys", m.CreateAPIKeyRequest{Name: "frontend", Role: e.RoleServing},
+		"admin-key")
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", rr.Code, rr.Body)
+	}
+	var created m.CreateAPIKeyResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+
+	rr = a.do(t, http.MethodPost, "/v1/slots/1/banners", m.AddSlotBannerRequest{BannerID: 1}, created.Key)
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("Expected serving key to be forbidden from adding banners, got %d", rr.Code)
+	}
+
+	a.do(t, http.MethodPost, "/v1/slots/1/banners", m.AddSlotBannerRequest{BannerID: 1}, "admin-key")
+	rr = a.do(t, http.MethodPost, "/v1/slots/1/selections", m.CreateSelectionRequest{UserGroupID: 1}, created.Key)
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected serving key to select banners, got %d: %s", rr.Code, rr.Body)
+	}
+
+	a.do(t, http.MethodDelete, "/v1/admin/api-keys/1", nil, "admin-key")
+	rr = a.do(t, http.MethodPost, "/v1/slots/1/selections", m.CreateSelectionRequest{UserGroupID: 1}, created.Key)
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("Expected revoked key to be rejected, got %d", rr.Code)
+	}
+}
+
+func TestIPRateLimitBeforeAuth(t *testing.T) {
+	a := setupTestAPI(t)
+	InitAuth(nil, "admin-key")
+	InitIPRateLimit(&ratelimit.Limit{Rate: 0.001, Burst: 1})
+	t.Cleanup(func() { InitIPRateLimit(nil) })
+
+	rr := a.do(t, http.MethodPost, "/v1/slots/1/selections", m.CreateSelectionRequest{UserGroupID: 1}, "bad-key")
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for an invalid key, got %d", rr.Code)
+	}
+	rr = a.do(t, http.MethodPost, "/v1/slots/1/selections", m.CreateSelectionRequest{UserGroupID: 1}, "bad-key")
+	if rr.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected requests with invalid keys to be rate limited, got %d", rr.Code)
+	}
+}
+
+// slowStatisticRepository fails every view increment as if Postgres had not
+// answered before the query deadline.
+type slowStatisticRepository struct {
+	*statisticrepository.MemStatisticRepository
+}
+
+func (slowStatisticRepository) IncrementView(context.Context, e.SlotID, e.BannerID, e.UserGroupID) error {
+	return fmt.Errorf("%w: %w", repository.ErrQueryTimeout, context.DeadlineExceeded)
+}
+
+func TestQueryTimeout(t *testing.T) {
+	a := setupTestAPI(t)
+	a.do(t, http.MethodPost, "/v1/slots/1/banners", m.AddSlotBannerRequest{BannerID: 1}, "")
+
+	a.repos.Statistics = slowStatisticRepository{a.statistics}
+	SetRepositories(a.repos)
+
+	rr := a.do(t, http.MethodPost, "/v1/slots/1/selections", m.CreateSelectionRequest{UserGroupID: 1}, "")
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 on a query timeout, got %d: %s", rr.Code, rr.Body)
+	}
+}
+
+func TestReload(t *testing.T) {
+	a := setupTestAPI(t)
+	ctx := context.Background()
+
+	// A banner added to the database behind the service's back.
+	if err := a.repos.SlotBanners.AddBannerToSlot(ctx, 3, 9); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.repos.Statistics.CreateStartStatisticsForBannerInSlot(ctx, 3, 9,
+		[]e.UserGroupID{1, 2}); err != nil {
+		t.Fatal(err)
+	}
+	a.repos.Statistics.UpdateStatistics(ctx, &e.Statistics{SlotID: 3, BannerID: 9, UserGroupID: 2, Views: 7, Clicks: 3})
+
+	rr := a.do(t, http.MethodPost, "/v1/slots/3/selections", m.CreateSelectionRequest{UserGroupID: 1}, "")
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("Expected 404 before reload, got %d", rr.Code)
+	}
+
+	rr = a.do(t, http.MethodPost, "/admin/reload", nil, "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body)
+	}
+	var reloaded m.ReloadResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &reloaded); err != nil {
+		t.Fatal(err)
+	}
+	if reloaded.Slots != 3 || reloaded.Banners != 1 {
+		t.Errorf("Unexpected reload result %+v", reloaded)
+	}
+
+	if stats, _ := banditService.GetStats(3, 9, 2); stats.Views != 7 || stats.Clicks != 3 {
+		t.Errorf("Expected statistics of every user group to be loaded, got %+v", stats)
+	}
+
+	rr = a.do(t, http.MethodPost, "/v1/slots/3/selections", m.CreateSelectionRequest{UserGroupID: 1}, "")
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected the reloaded banner to be selected, got %d: %s", rr.Code, rr.Body)
+	}
+}
+
+func TestPauseAndSchedule(t *testing.T) {
+	a := setupTestAPI(t)
+
+	rr := a.do(t, http.MethodPost, "/v1/slots/1/banners", m.AddSlotBannerRequest{BannerID: 1}, "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body)
+	}
+
+	rr = a.do(t, http.MethodPut, "/v1/slots/1/banners/1", m.UpdateSlotBannerRequest{Status: e.BannerPaused}, "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body)
+	}
+	rr = a.do(t, http.MethodPost, "/v1/slots/1/selections", m.CreateSelectionRequest{UserGroupID: 1}, "")
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected a paused banner not to be selected, got %d", rr.Code)
+	}
+
+	// Resuming the banner with a flight that has already ended keeps it out of rotation.
+	past := time.Now().Add(-time.Hour)
+	rr = a.do(t, http.MethodPut, "/v1/slots/1/banners/1", m.UpdateSlotBannerRequest{EndsAt: &past}, "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body)
+	}
+	rr = a.do(t, http.MethodPost, "/v1/slots/1/selections", m.CreateSelectionRequest{UserGroupID: 1}, "")
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected a banner past its flight not to be selected, got %d", rr.Code)
+	}
+
+	rr = a.do(t, http.MethodPut, "/v1/slots/1/banners/1", m.UpdateSlotBannerRequest{Status: e.BannerActive}, "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body)
+	}
+	rr = a.do(t, http.MethodPost, "/v1/slots/1/selections", m.CreateSelectionRequest{UserGroupID: 1}, "")
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected a resumed banner to be selected, got %d: %s", rr.Code, rr.Body)
+	}
+
+	rr = a.do(t, http.MethodGet, "/v1/slots/1/banners", nil, "")
+	var slotBanners []e.SlotBanner
+	if err := json.Unmarshal(rr.Body.Bytes(), &slotBanners); err != nil {
+		t.Fatal(err)
+	}
+	if len(slotBanners) != 1 || slotBanners[0].Status != e.BannerActive || slotBanners[0].EndsAt != nil {
+		t.Errorf("Unexpected banners of slot %+v", slotBanners)
+	}
+
+	rr = a.do(t, http.MethodPut, "/v1/slots/1/banners/2", m.UpdateSlotBannerRequest{}, "")
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a banner not in the slot, got %d", rr.Code)
+	}
+	rr = a.do(t, http.MethodPut, "/v1/slots/1/banners/1", m.UpdateSlotBannerRequest{Status: "stopped"}, "")
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown status, got %d", rr.Code)
+	}
+}
+
+func TestDailyImpressionCap(t *testing.T) {
+	a := setupTestAPI(t)
+
+	a.do(t, http.MethodPost, "/v1/slots/1/banners", m.AddSlotBannerRequest{BannerID: 1}, "")
+	rr := a.do(t, http.MethodPut, "/v1/slots/1/banners/1", m.UpdateSlotBannerRequest{MaxDailyImpressions: 2}, "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body)
+	}
+
+	for i := 0; i < 2; i++ {
+		request := m.CreateSelectionRequest{UserGroupID: e.UserGroupID(i%2 + 1)}
+		rr = a.do(t, http.MethodPost, "/v1/slots/1/selections", request, "")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body)
+		}
+	}
+	rr = a.do(t, http.MethodPost, "/v1/slots/1/selections", m.CreateSelectionRequest{UserGroupID: 1}, "")
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected a banner at its cap not to be selected, got %d", rr.Code)
+	}
+
+	rr = a.do(t, http.MethodGet, "/v1/slots/1/delivery", nil, "")
+	var deliveries []m.BannerDelivery
+	if err := json.Unmarshal(rr.Body.Bytes(), &deliveries); err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 1 || deliveries[0].Impressions != 2 || deliveries[0].RemainingImpressions == nil ||
+		*deliveries[0].RemainingImpressions != 0 {
+		t.Errorf("Unexpected delivery %+v", deliveries)
+	}
+
+	rr = a.do(t, http.MethodPut, "/v1/slots/1/banners/1",
+		m.UpdateSlotBannerRequest{MinDailyImpressions: 5, MaxDailyImpressions: 2}, "")
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a minimum above the cap, got %d", rr.Code)
+	}
+}
+
+func TestFrequencyCap(t *testing.T) {
+	a := setupTestAPI(t)
+	InitFrequencyCap(2, time.Hour)
+
+	a.do(t, http.MethodPost, "/v1/slots/1/banners", m.AddSlotBannerRequest{BannerID: 1}, "")
+	a.do(t, http.MethodPost, "/v1/slots/1/banners", m.AddSlotBannerRequest{BannerID: 2}, "")
+
+	shown := make(map[e.BannerID]int)
+	for i := 0; i < 4; i++ {
+		rr := a.do(t, http.MethodPost, "/v1/slots/1/selections",
+			m.CreateSelectionRequest{UserGroupID: 1, UserID: "user-1"}, "")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body)
+		}
+		var response m.SelectBannerResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		shown[response.BannerID]++
+	}
+	if shown[1] != 2 || shown[2] != 2 {
+		t.Errorf("Expected each banner to be shown to the user twice, got %v", shown)
+	}
+
+	rr := a.do(t, http.MethodPost, "/v1/slots/1/selections",
+		m.CreateSelectionRequest{UserGroupID: 1, UserID: "user-1"}, "")
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected no banner once the user saw every one, got %d", rr.Code)
+	}
+
+	for _, request := range []m.CreateSelectionRequest{{UserGroupID: 1, UserID: "user-2"}, {UserGroupID: 1}} {
+		rr = a.do(t, http.MethodPost, "/v1/slots/1/selections", request, "")
+		if rr.Code != http.StatusOK {
+			t.Errorf("Expected other and anonymous users not to be capped, got %d", rr.Code)
+		}
+	}
+
+	// Selections count towards the cap only once their view is confirmed.
+	InitViewConfirmation(time.Hour)
+	var impressionIDs []string
+	for i := 0; i < 3; i++ {
+		rr = a.do(t, http.MethodPost, "/v1/slots/1/selections",
+			m.CreateSelectionRequest{UserGroupID: 1, UserID: "user-3"}, "")
+		var response m.SelectBannerResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		impressionIDs = append(impressionIDs, response.ImpressionID)
+	}
+	if capped := frequencyCapper.Capped(1, "user-3", time.Now()); len(capped) != 0 {
+		t.Errorf("Expected unconfirmed selections not to count towards the cap, got %v capped", capped)
+	}
+	for _, impressionID := range impressionIDs {
+		a.do(t, http.MethodGet, "/pixel/view?impressionId="+impressionID, nil, "")
+	}
+	if capped := frequencyCapper.Capped(1, "user-3", time.Now()); len(capped) == 0 {
+		t.Error("Expected three confirmed views of two banners to cap one of them")
+	}
+}
+
+func TestBannerCreative(t *testing.T) {
+	a := setupTestAPI(t)
+
+	creative := e.Creative{
+		ImageURL:  "https://cdn.example.com/banners/spring.png",
+		AltText:   "Spring sale",
+		TargetURL: "https://shop.example.com/spring",
+		Width:     728,
+		Height:    90,
+		Format:    e.FormatPNG,
+	}
+	rr := a.do(t, http.MethodPost, "/v1/banners", m.CreateBannerRequest{Description: "spring", Creative: &creative}, "")
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", rr.Code, rr.Body)
+	}
+	var banner e.Banner
+	if err := json.Unmarshal(rr.Body.Bytes(), &banner); err != nil {
+		t.Fatal(err)
+	}
+
+	a.do(t, http.MethodPost, "/v1/slots/1/banners", m.AddSlotBannerRequest{BannerID: banner.ID}, "")
+	rr = a.do(t, http.MethodPost, "/v1/slots/1/selections", m.CreateSelectionRequest{UserGroupID: 1}, "")
+	var response m.SelectBannerResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.BannerID != banner.ID || response.Creative == nil || *response.Creative != creative {
+		t.Errorf("Expected the creative to be returned with the selection, got %+v", response)
+	}
+
+	rr = a.do(t, http.MethodGet, fmt.Sprintf("/v1/banners/%d", banner.ID), nil, "")
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected 200, got %d: %s", rr.Code, rr.Body)
+	}
+
+	invalid := []e.Creative{
+		{ImageURL: "/spring.png", TargetURL: creative.TargetURL, Width: 1, Height: 1, Format: e.FormatPNG},
+		{ImageURL: creative.ImageURL, TargetURL: "javascript:alert(1)", Width: 1, Height: 1, Format: e.FormatPNG},
+		{ImageURL: creative.ImageURL, TargetURL: creative.TargetURL, Width: 0, Height: 1, Format: e.FormatPNG},
+		{ImageURL: creative.ImageURL, TargetURL: creative.TargetURL, Width: 1, Height: 1, Format: "bmp"},
+	}
+	for _, c := range invalid {
+		rr = a.do(t, http.MethodPost, "/v1/banners", m.CreateBannerRequest{Creative: &c}, "")
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for creative %+v, got %d", c, rr.Code)
+		}
+	}
+}
+
+func TestClickRedirect(t *testing.T) {
+	a := setupTestAPI(t)
+
+	creative := &e.Creative{
+		ImageURL: "https://cdn.example.com/a.gif", TargetURL: "https://shop.example.com/a",
+		Width: 1, Height: 1, Format: e.FormatGIF,
+	}
+	rr := a.do(t, http.MethodPost, "/v1/banners", m.CreateBannerRequest{Creative: creative}, "")
+	var banner e.Banner
+	if err := json.Unmarshal(rr.Body.Bytes(), &banner); err != nil {
+		t.Fatal(err)
+	}
+	a.do(t, http.MethodPost, "/v1/slots/1/banners", m.AddSlotBannerRequest{BannerID: banner.ID}, "")
+
+	rr = a.do(t, http.MethodPost, "/v1/slots/1/selections", m.CreateSelectionRequest{UserGroupID: 2}, "")
+	var selection m.SelectBannerResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &selection); err != nil {
+		t.Fatal(err)
+	}
+	if selection.ImpressionID == "" {
+		t.Fatal("Expected the selection to return an impression ID")
+	}
+
+	rr = a.do(t, http.MethodGet, "/click/"+selection.ImpressionID, nil, "")
+	if rr.Code != http.StatusFound || rr.Header().Get("Location") != creative.TargetURL {
+		t.Fatalf("Expected a redirect to the target URL, got %d to %q", rr.Code, rr.Header().Get("Location"))
+	}
+	if stats, _ := banditService.GetStats(1, banner.ID, 2); stats.Clicks != 1 {
+		t.Errorf("Expected the click to be recorded, got %+v", stats)
+	}
+
+	a.do(t, http.MethodGet, "/click/"+selection.ImpressionID, nil, "")
+	a.do(t, http.MethodGet, "/pixel/click?impressionId="+selection.ImpressionID, nil, "")
+	if stats, _ := banditService.GetStats(1, banner.ID, 2); stats.Clicks != 1 {
+		t.Errorf("Expected replayed clicks not to be counted, got %+v", stats)
+	}
+
+	rr = a.do(t, http.MethodGet, "/click/"+selection.ImpressionID+"x", nil, "")
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a tampered impression ID, got %d", rr.Code)
+	}
+
+	impressionTTL = 0
+	rr = a.do(t, http.MethodGet, "/click/"+selection.ImpressionID, nil, "")
+	if rr.Code != http.StatusFound {
+		t.Errorf("Expected an expired impression to still redirect, got %d", rr.Code)
+	}
+	if stats, _ := banditService.GetStats(1, banner.ID, 2); stats.Clicks != 1 {
+		t.Errorf("Expected a click on an expired impression not to be counted, got %+v", stats)
+	}
+}
+
+func TestTrackingPixels(t *testing.T) {
+	a := setupTestAPI(t)
+
+	a.do(t, http.MethodPost, "/v1/slots/1/banners", m.AddSlotBannerRequest{BannerID: 1}, "")
+	rr := a.do(t, http.MethodPost, "/v1/slots/1/selections", m.CreateSelectionRequest{UserGroupID: 1}, "")
+	var selection m.SelectBannerResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &selection); err != nil {
+		t.Fatal(err)
+	}
+	query := "?impressionId=" + selection.ImpressionID + "&slotId=1&bannerId=1&userGroupId=1"
+
+	for _, path := range []string{"/pixel/view", "/pixel/click"} {
+		rr = a.do(t, http.MethodGet, path+query, nil, "")
+		if rr.Code != http.StatusOK {
+			t.Errorf("%s: expected 200, got %d", path, rr.Code)
+		}
+		if rr.Header().Get("Content-Type") != "image/gif" || rr.Header().Get("Cache-Control") == "" {
+			t.Errorf("%s: unexpected headers %v", path, rr.Header())
+		}
+		img, err := gif.Decode(rr.Body)
+		if err != nil {
+			t.Fatalf("%s: expected a GIF, got %v", path, err)
+		}
+		if bounds := img.Bounds(); bounds.Dx() != 1 || bounds.Dy() != 1 {
+			t.Errorf("%s: expected a 1x1 image, got %v", path, bounds)
+		}
+	}
+	if stats, _ := banditService.GetStats(1, 1, 1); stats.Clicks != 1 || stats.Views != 1 {
+		t.Errorf("Expected the click pixel to record one click, got %+v", stats)
+	}
+
+	rr = a.do(t, http.MethodGet, "/pixel/click?impressionId="+selection.ImpressionID+"&bannerId=2", nil, "")
+	if rr.Code != http.StatusBadRequest || rr.Header().Get("Content-Type") != "image/gif" {
+		t.Errorf("Expected 400 with the GIF for a mismatched banner, got %d", rr.Code)
+	}
+	rr = a.do(t, http.MethodGet, "/pixel/click?slotId=1&bannerId=1&userGroupId=1", nil, "")
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 without an impression ID, got %d", rr.Code)
+	}
+}
+
+func TestViewConfirmation(t *testing.T) {
+	a := setupTestAPI(t)
+	InitViewConfirmation(time.Hour)
+
+	a.do(t, http.MethodPost, "/v1/slots/1/banners", m.AddSlotBannerRequest{BannerID: 1}, "")
+	rr := a.do(t, http.MethodPost, "/v1/slots/1/selections", m.CreateSelectionRequest{UserGroupID: 1}, "")
+	var selection m.SelectBannerResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &selection); err != nil {
+		t.Fatal(err)
+	}
+	if stats, _ := banditService.GetStats(1, 1, 1); stats.Views != 0 {
+		t.Errorf("Expected no view before confirmation, got %+v", stats)
+	}
+
+	for range 2 {
+		rr = a.do(t, http.MethodPost, "/v1/impressions/"+selection.ImpressionID+"/views", nil, "")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+	}
+	if stats, _ := banditService.GetStats(1, 1, 1); stats.Views != 1 {
+		t.Errorf("Expected the view to be counted once, got %+v", stats)
+	}
+
+	rr = a.do(t, http.MethodPost, "/record-view", m.RecordViewRequest{ImpressionID: "invalid"}, "")
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid impression ID, got %d", rr.Code)
+	}
+
+	InitViewConfirmation(time.Nanosecond)
+	rr = a.do(t, http.MethodPost, "/record-view", m.RecordViewRequest{ImpressionID: selection.ImpressionID}, "")
+	if rr.Code != http.StatusGone {
+		t.Errorf("Expected 410 for an expired impression, got %d", rr.Code)
+	}
+
+	InitViewConfirmation(0)
+	rr = a.do(t, http.MethodPost, "/record-view", m.RecordViewRequest{ImpressionID: selection.ImpressionID}, "")
+	if rr.Code != http.StatusConflict {
+		t.Errorf("Expected 409 when views are counted on selection, got %d", rr.Code)
+	}
+}
+
+func TestConversions(t *testing.T) {
+	a := setupTestAPI(t)
+
+	a.do(t, http.MethodPost, "/v1/slots/1/banners", m.AddSlotBannerRequest{BannerID: 1}, "")
+	rr := a.do(t, http.MethodPost, "/v1/slots/1/banners/1/conversions",
+		m.CreateConversionRequest{UserGroupID: 1, Value: 12.5}, "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body)
+	}
+	rr = a.do(t, http.MethodPost, "/record-conversion",
+		m.RecordConversionRequest{SlotID: 1, BannerID: 1, UserGroupID: 1}, "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body)
+	}
+	stat, err := a.statistics.GetStatistics(context.Background(), 1, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.Conversions != 2 || stat.Revenue != 12.5 {
+		t.Errorf("Expected 2 conversions worth 12.5, got %+v", stat)
+	}
+
+	rr = a.do(t, http.MethodPost, "/v1/slots/1/banners/1/conversions",
+		m.CreateConversionRequest{UserGroupID: 1, Value: -1}, "")
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a negative value, got %d", rr.Code)
+	}
+
+	rr = a.do(t, http.MethodPut, "/v1/slots/1", m.UpdateSlotRequest{Goal: e.GoalRevenue}, "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body)
+	}
+	rr = a.do(t, http.MethodGet, "/v1/slots/1", nil, "")
+	var slot e.Slot
+	if err := json.Unmarshal(rr.Body.Bytes(), &slot); err != nil {
+		t.Fatal(err)
+	}
+	if slot.Goal != e.GoalRevenue {
+		t.Errorf("Expected the revenue goal, got %q", slot.Goal)
+	}
+
+	rr = a.do(t, http.MethodPut, "/v1/slots/1", m.UpdateSlotRequest{Goal: "likes"}, "")
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown goal, got %d", rr.Code)
+	}
+	rr = a.do(t, http.MethodGet, "/v1/slots/9", nil, "")
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown slot, got %d", rr.Code)
+	}
+}
+
+func TestRotationStrategy(t *testing.T) {
+	a := setupTestAPI(t)
+
+	rr := a.do(t, http.MethodPut, "/v1/slots/1", m.UpdateSlotRequest{Goal: e.GoalRevenue, Strategy: e.StrategyUCBV}, "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body)
+	}
+	var slot e.Slot
+	if err := json.Unmarshal(rr.Body.Bytes(), &slot); err != nil {
+		t.Fatal(err)
+	}
+	if slot.Goal != e.GoalRevenue || slot.Strategy != e.StrategyUCBV {
+		t.Errorf("Expected the revenue goal with UCB-V, got %+v", slot)
+	}
+
+	rr = a.do(t, http.MethodPut, "/v1/slots/1", m.UpdateSlotRequest{Strategy: "epsilon-greedy"}, "")
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown strategy, got %d", rr.Code)
+	}
+
+	// The update replaces every setting, so the omitted goal and strategy are reset.
+	rr = a.do(t, http.MethodPut, "/v1/slots/1", m.UpdateSlotRequest{HoldoutPercent: 10}, "")
+	slot = e.Slot{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &slot); err != nil {
+		t.Fatal(err)
+	}
+	if slot.Goal != e.GoalCTR || slot.Strategy != e.StrategyUCB1 || slot.HoldoutPercent != 10 {
+		t.Errorf("Expected the ctr goal with UCB1 and a 10%% holdout, got %+v", slot)
+	}
+	a.do(t, http.MethodPut, "/v1/slots/1", m.UpdateSlotRequest{Goal: e.GoalRevenue, Strategy: e.StrategyUCBV}, "")
+
+	a.do(t, http.MethodPost, "/v1/slots/1/banners", m.AddSlotBannerRequest{BannerID: 1}, "")
+	a.do(t, http.MethodPost, "/v1/slots/1/banners/1/conversions", m.CreateConversionRequest{UserGroupID: 1, Value: 3}, "")
+	stat, err := a.statistics.GetStatistics(context.Background(), 1, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.RevenueSquares != 9 {
+		t.Errorf("Expected revenue squares of 9, got %+v", stat)
+	}
+	if stats, _ := banditService.GetStats(1, 1, 1); stats.RevenueSquares != 9 {
+		t.Errorf("Expected the bandit to learn the revenue squares, got %+v", stats)
+	}
+}
+
+func TestHoldoutLift(t *testing.T) {
+	a := setupTestAPI(t)
+
+	a.do(t, http.MethodPost, "/v1/slots/1/banners", m.AddSlotBannerRequest{BannerID: 1}, "")
+	rr := a.do(t, http.MethodPut, "/v1/slots/1", m.UpdateSlotRequest{HoldoutPercent: 100}, "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body)
+	}
+
+	// Two control views, one clicked with its impression ID.
+	var selection m.SelectBannerResponse
+	for i := 0; i < 2; i++ {
+		rr = a.do(t, http.MethodPost, "/v1/slots/1/selections", m.CreateSelectionRequest{UserGroupID: 1}, "")
+		if err := json.Unmarshal(rr.Body.Bytes(), &selection); err != nil {
+			t.Fatal(err)
+		}
+		if !selection.Control {
+			t.Fatalf("Expected a control selection, got %+v", selection)
+		}
+	}
+	rr = a.do(t, http.MethodPost, "/v1/slots/1/banners/1/clicks",
+		m.CreateClickRequest{UserGroupID: 2, ImpressionID: selection.ImpressionID}, "")
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an impression ID of another user group, got %d", rr.Code)
+	}
+	a.do(t, http.MethodPost, "/v1/slots/1/banners/1/clicks",
+		m.CreateClickRequest{UserGroupID: 1, ImpressionID: selection.ImpressionID}, "")
+
+	// Two bandit views, both clicked. An empty update resets the holdout to 0.
+	a.do(t, http.MethodPut, "/v1/slots/1", m.UpdateSlotRequest{}, "")
+	for i := 0; i < 2; i++ {
+		rr = a.do(t, http.MethodPost, "/v1/slots/1/selections", m.CreateSelectionRequest{UserGroupID: 1}, "")
+		selection = m.SelectBannerResponse{}
+		if err := json.Unmarshal(rr.Body.Bytes(), &selection); err != nil {
+			t.Fatal(err)
+		}
+		if selection.Control {
+			t.Fatalf("Expected a bandit selection, got %+v", selection)
+		}
+		a.do(t, http.MethodPost, "/v1/slots/1/banners/1/clicks", m.CreateClickRequest{UserGroupID: 1}, "")
+	}
+
+	rr = a.do(t, http.MethodGet, "/v1/slots/1/lift", nil, "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body)
+	}
+	var report m.LiftReport
+	if err := json.Unmarshal(rr.Body.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Bandit != (m.TrafficStats{Views: 2, Clicks: 2, CTR: 1}) ||
+		report.Control != (m.TrafficStats{Views: 2, Clicks: 1, CTR: 0.5}) {
+		t.Errorf("Unexpected traffic in %+v", report)
+	}
+	if report.Lift == nil || *report.Lift != 1 || report.ZScore == nil || *report.ZScore <= 0 {
+		t.Errorf("Expected a lift of 1 with a positive z-score, got %+v", report)
+	}
+
+	rr = a.do(t, http.MethodPut, "/v1/slots/1", m.UpdateSlotRequest{HoldoutPercent: 101}, "")
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a holdout over 100%%, got %d", rr.Code)
+	}
+}
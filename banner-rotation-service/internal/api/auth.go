@@ -0,0 +1,136 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/yuriiwanchev/banner-rotation-service/internal/auth"
+	e "github.com/yuriiwanchev/banner-rotation-service/internal/entities"
+	m "github.com/yuriiwanchev/banner-rotation-service/internal/models"
+)
+
+var authenticator *auth.Authenticator
+
+// InitAuth enables authentication of API requests. Without it every request is
+// treated as coming from an admin. API keys are looked up through the
+// repository set up by InitRepositories, so InitAuth may be called first.
+func InitAuth(jwtSecret []byte, bootstrapKey string) {
+	authenticator = auth.NewAuthenticator(apiKeyStore{}, jwtSecret, bootstrapKey)
+}
+
+// apiKeyStore resolves keys through whichever repository is current.
+type apiKeyStore struct{}
+
+func (apiKeyStore) GetAPIKeyByHash(ctx context.Context, keyHash string) (*e.APIKey, error) {
+	return apiKeyRepository.GetAPIKeyByHash(ctx, keyHash)
+}
+
+// Authorize authenticates the token and checks that the caller has role.
+func Authorize(ctx context.Context, token string, role e.Role) (*auth.Principal, error) {
+	if authenticator == nil {
+		return &auth.Principal{Subject: "anonymous", Role: e.RoleAdmin}, nil
+	}
+
+	principal, err := authenticator.Authenticate(ctx, token)
+	if errors.Is(err, auth.ErrUnauthenticated) {
+		return nil, newRequestError(http.StatusUnauthorized, "Missing or invalid credentials")
+	}
+	if err != nil {
+		return nil, storageError(err, "Failed to check credentials")
+	}
+	if !principal.Allows(role) {
+		return nil, newRequestError(http.StatusForbidden, "Insufficient role")
+	}
+	return principal, nil
+}
+
+// RequireRole rejects requests whose credentials do not grant role.
+// Credentials are taken from "Authorization: Bearer" or "X-API-Key".
+func RequireRole(role e.Role, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := auth.TokenFromHeader(r.Header.Get("Authorization"))
+		if token == "" {
+			token = r.Header.Get("X-API-Key")
+		}
+
+		principal, err := Authorize(r.Context(), token, role)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="banner-rotation-service"`)
+			errorResponse(w, err)
+			return
+		}
+
+		next(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+	}
+}
+
+func CreateAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	var request m.CreateAPIKeyRequest
+
+	if err := decodeJSON(w, r, &request); err != nil {
+		errorResponse(w, err)
+		return
+	}
+
+	if request.Name == "" || !auth.ValidRole(request.Role) {
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "Name and a valid Role are required"})
+		return
+	}
+
+	key, err := auth.GenerateKey()
+	if err != nil {
+		log.Println(err)
+		jsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to generate api key"})
+		return
+	}
+
+	apiKey := &e.APIKey{Name: request.Name, Role: request.Role}
+	id, err := apiKeyRepository.CreateAPIKey(r.Context(), apiKey, auth.HashKey(key))
+	if err != nil {
+		errorResponse(w, storageError(err, "Failed to save api key to db"))
+		return
+	}
+
+	jsonResponse(w, http.StatusCreated, m.CreateAPIKeyResponse{
+		ID:   id,
+		Name: request.Name,
+		Role: request.Role,
+		Key:  key,
+	})
+}
+
+func ListAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	keys, err := apiKeyRepository.GetAllAPIKeys(r.Context())
+	if err != nil {
+		errorResponse(w, storageError(err, "Failed to get api keys from db"))
+		return
+	}
+
+	if keys == nil {
+		keys = []*e.APIKey{}
+	}
+	jsonResponse(w, http.StatusOK, keys)
+}
+
+func RevokeAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("keyId"))
+	if err != nil || id <= 0 {
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "invalid keyId in path"})
+		return
+	}
+
+	if err := apiKeyRepository.RevokeAPIKey(r.Context(), e.APIKeyID(id)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			jsonResponse(w, http.StatusNotFound, map[string]string{"error": "API key not found"})
+			return
+		}
+		errorResponse(w, storageError(err, "Failed to revoke api key"))
+		return
+	}
+
+	jsonResponse(w, http.StatusOK, nil)
+}
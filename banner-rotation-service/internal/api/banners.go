@@ -0,0 +1,52 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+
+	e "github.com/yuriiwanchev/banner-rotation-service/internal/entities"
+)
+
+// bannerCache keeps the banners returned from selections, so that the
+// creative of a selected banner is not read from the database every time.
+// Banners unknown to the database are cached as nil.
+type bannerCache struct {
+	mu      sync.RWMutex
+	banners map[e.BannerID]*e.Banner
+}
+
+var banners = &bannerCache{banners: make(map[e.BannerID]*e.Banner)}
+
+// get returns the banner, reading it from the database on first use.
+func (c *bannerCache) get(ctx context.Context, id e.BannerID) (*e.Banner, error) {
+	c.mu.RLock()
+	banner, cached := c.banners[id]
+	c.mu.RUnlock()
+	if cached {
+		return banner, nil
+	}
+
+	banner, err := bannerRepository.GetBannerByID(ctx, id)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	c.set(id, banner)
+	return banner, nil
+}
+
+func (c *bannerCache) set(id e.BannerID, banner *e.Banner) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.banners[id] = banner
+}
+
+// clear drops every cached banner, e.g. to pick up creatives changed in the database.
+func (c *bannerCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.banners = make(map[e.BannerID]*e.Banner)
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/yuriiwanchev/banner-rotation-service/internal/cluster"
+	e "github.com/yuriiwanchev/banner-rotation-service/internal/entities"
+)
+
+var (
+	instanceID string
+	// replicator is published atomically, as /readyz may already be served.
+	replicator atomic.Pointer[cluster.Replicator]
+)
+
+// InitCluster tags the events of this instance with id and returns a
+// replicator applying the events of other replicas. It must be run before the
+// rotation algorithm is initialized, so that no selection publishes untagged
+// events, and the replicator run only after that.
+func InitCluster(id string, events cluster.Subscriber, maxStaleness time.Duration) *cluster.Replicator {
+	instanceID = id
+	r := cluster.NewReplicator(id, banditApplier{}, eventPublisher{}, events, maxStaleness)
+	replicator.Store(r)
+	return r
+}
+
+// banditApplier applies replicated events to whichever bandit is current.
+type banditApplier struct{}
+
+func (banditApplier) RecordView(slotID e.SlotID, bannerID e.BannerID, groupID e.UserGroupID) error {
+	return banditService.RecordView(slotID, bannerID, groupID)
+}
+
+func (banditApplier) RecordUserView(slotID e.SlotID, bannerID e.BannerID, userID string) {
+	recordUserView(slotID, bannerID, userID)
+}
+
+func (banditApplier) RecordConversion(slotID e.SlotID, bannerID e.BannerID, groupID e.UserGroupID,
+	value float64,
+) error {
+	return banditService.RecordConversion(slotID, bannerID, groupID, value)
+}
+
+func (banditApplier) RecordClick(slotID e.SlotID, bannerID e.BannerID, groupID e.UserGroupID) error {
+	return banditService.RecordClick(slotID, bannerID, groupID)
+}
+
+type eventPublisher struct{}
+
+func (eventPublisher) PublishEvent(event e.Event) error {
+	producer := kafkaProducer.Load()
+	if producer == nil {
+		return errors.New("kafka producer is not initialized")
+	}
+	return producer.PublishEvent(event)
+}
@@ -0,0 +1,35 @@
+package api
+
+import (
+	"time"
+
+	e "github.com/yuriiwanchev/banner-rotation-service/internal/entities"
+	"github.com/yuriiwanchev/banner-rotation-service/internal/logic/frequency"
+)
+
+var frequencyCapper *frequency.Capper
+
+// InitFrequencyCap limits how many times a user is shown the same banner of a
+// slot within window. A limit of 0 disables the cap.
+func InitFrequencyCap(limit int, window time.Duration) {
+	if limit <= 0 {
+		frequencyCapper = nil
+		return
+	}
+	frequencyCapper = frequency.NewCapper(limit, window)
+}
+
+// cappedBanners returns the banners of the slot the user has been shown too often.
+func cappedBanners(slotID e.SlotID, userID string) []e.BannerID {
+	if frequencyCapper == nil || userID == "" {
+		return nil
+	}
+	return frequencyCapper.Capped(slotID, userID, time.Now())
+}
+
+func recordUserView(slotID e.SlotID, bannerID e.BannerID, userID string) {
+	if frequencyCapper == nil || userID == "" {
+		return
+	}
+	frequencyCapper.Record(slotID, bannerID, userID, time.Now())
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	m "github.com/yuriiwanchev/banner-rotation-service/internal/models"
+	"github.com/yuriiwanchev/banner-rotation-service/internal/repository"
+)
+
+const readinessCheckTimeout = 2 * time.Second
+
+var (
+	buildInfo     m.BuildInfo
+	rotationReady atomic.Bool
+)
+
+func InitBuildInfo(release, buildDate, gitHash string) {
+	buildInfo = m.BuildInfo{
+		Release:   release,
+		BuildDate: buildDate,
+		GitHash:   gitHash,
+	}
+}
+
+// RequireReady rejects requests with 503 until the rotation algorithm has been warm-started.
+func RequireReady(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !rotationReady.Load() {
+			jsonResponse(w, http.StatusServiceUnavailable, map[string]string{"error": "Service is starting"})
+			return
+		}
+		next(w, r)
+	}
+}
+
+func HealthzHandler(w http.ResponseWriter, _ *http.Request) {
+	jsonResponse(w, http.StatusOK, m.HealthResponse{Status: "ok"})
+}
+
+// ReadyzHandler also fails in cluster mode while the instance lags the event
+// stream of the other replicas by more than the allowed staleness.
+func ReadyzHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessCheckTimeout)
+	defer cancel()
+
+	response := m.HealthResponse{
+		Status: "ok",
+		Checks: map[string]string{
+			"database": "ok",
+			"kafka":    "ok",
+			"bandit":   "ok",
+		},
+	}
+
+	if db := repository.GetDB(); db == nil {
+		response.Checks["database"] = "not initialized"
+	} else if err := db.PingContext(ctx); err != nil {
+		response.Checks["database"] = err.Error()
+	}
+
+	if producer := kafkaProducer.Load(); producer == nil {
+		response.Checks["kafka"] = "not initialized"
+	} else if err := producer.Ping(ctx); err != nil {
+		response.Checks["kafka"] = err.Error()
+	}
+
+	if !rotationReady.Load() {
+		response.Checks["bandit"] = "warming up"
+	}
+
+	if r := replicator.Load(); r != nil {
+		response.Checks["cluster"] = "ok"
+		if err := r.Healthy(); err != nil {
+			response.Checks["cluster"] = err.Error()
+		}
+	}
+
+	status := http.StatusOK
+	for _, check := range response.Checks {
+		if check != "ok" {
+			response.Status = "unavailable"
+			status = http.StatusServiceUnavailable
+			break
+		}
+	}
+
+	jsonResponse(w, status, response)
+}
+
+func VersionHandler(w http.ResponseWriter, _ *http.Request) {
+	jsonResponse(w, http.StatusOK, buildInfo)
+}
+
+// Ready reports whether the rotation algorithm has been warm-started.
+func Ready() bool {
+	return rotationReady.Load()
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	e "github.com/yuriiwanchev/banner-rotation-service/internal/entities"
+	"github.com/yuriiwanchev/banner-rotation-service/internal/impression"
+	"github.com/yuriiwanchev/banner-rotation-service/internal/logic/bandit"
+	m "github.com/yuriiwanchev/banner-rotation-service/internal/models"
+)
+
+var (
+	impressionSigner *impression.Signer
+	impressionTTL    time.Duration
+	// clickedImpressions makes replaying the ID of a clicked impression count
+	// no further clicks.
+	clickedImpressions *seenImpressions
+)
+
+// InitImpressions makes selections return impression IDs signed with key.
+// The first click on an impression is counted for ttl after the selection.
+func InitImpressions(key []byte, ttl time.Duration) {
+	impressionSigner = impression.NewSigner(key)
+	impressionTTL = ttl
+	clickedImpressions = &seenImpressions{
+		ttl:       ttl,
+		shownAt:   make(map[uint64]time.Time),
+		lastSweep: time.Now(),
+	}
+}
+
+// seenImpressions remembers impressions by nonce until they are older than
+// ttl, after which their clicks are not counted anyway.
+type seenImpressions struct {
+	ttl time.Duration
+
+	mu        sync.Mutex
+	shownAt   map[uint64]time.Time
+	lastSweep time.Time
+}
+
+// add returns false if the impression has been added already.
+func (s *seenImpressions) add(imp impression.Impression) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now := time.Now(); now.Sub(s.lastSweep) >= s.ttl/4 {
+		for nonce, shownAt := range s.shownAt {
+			if now.Sub(shownAt) > s.ttl {
+				delete(s.shownAt, nonce)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	if _, seen := s.shownAt[imp.Nonce]; seen {
+		return false
+	}
+	s.shownAt[imp.Nonce] = imp.ShownAt
+	return true
+}
+
+// clickedImpression returns the impression a click refers to by its ID, which
+// must be of the clicked slot, banner and user group.
+func clickedImpression(request m.RecordClickRequest) (impression.Impression, error) {
+	if impressionSigner == nil {
+		return impression.Impression{}, newRequestError(http.StatusBadRequest, "Impression IDs are not enabled")
+	}
+	imp, err := impressionSigner.Decode(request.ImpressionID)
+	if err != nil {
+		return imp, newRequestError(http.StatusBadRequest, "Invalid impression ID")
+	}
+	if imp.SlotID != request.SlotID || imp.BannerID != request.BannerID || imp.UserGroupID != request.UserGroupID {
+		return imp, newRequestError(http.StatusBadRequest, "impressionId does not match the click")
+	}
+	if time.Since(imp.ShownAt) > impressionTTL {
+		return imp, newRequestError(http.StatusGone, "Impression has expired")
+	}
+	return imp, nil
+}
+
+// issueImpression returns the impression of a selection and its ID, which is
+// empty unless impression IDs are enabled.
+func issueImpression(slotID e.SlotID, groupID e.UserGroupID,
+	selection bandit.Selection,
+) (impression.Impression, string) {
+	imp := impression.New(slotID, selection.BannerID, groupID, time.Now())
+	imp.Control = selection.Control
+	if impressionSigner == nil {
+		return imp, ""
+	}
+	return imp, impressionSigner.Encode(imp)
+}
+
+// ClickRedirectHandler handles GET /click/{impressionId}: it records a click
+// on the impression and redirects to the target URL of the banner, so that
+// placements can use a plain link. Clicks on expired impressions are not
+// counted, but still redirected.
+func ClickRedirectHandler(w http.ResponseWriter, r *http.Request) {
+	if impressionSigner == nil {
+		jsonResponse(w, http.StatusNotFound, map[string]string{"error": "Impression IDs are not enabled"})
+		return
+	}
+	imp, err := impressionSigner.Decode(r.PathValue("impressionId"))
+	if err != nil {
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid impression ID"})
+		return
+	}
+
+	banner, err := banners.get(r.Context(), imp.BannerID)
+	if err != nil {
+		errorResponse(w, storageError(err, "Failed to get banner from db"))
+		return
+	}
+	if banner == nil || banner.Creative == nil {
+		jsonResponse(w, http.StatusNotFound, map[string]string{"error": "Banner has no target URL"})
+		return
+	}
+
+	// The user is sent on even if the click cannot be recorded.
+	if err := recordImpressionClick(r.Context(), imp); err != nil {
+		log.Printf("Failed to record click on banner %d in slot %d: %v", imp.BannerID, imp.SlotID, err)
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, banner.Creative.TargetURL, http.StatusFound)
+}
@@ -0,0 +1,265 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/yuriiwanchev/banner-rotation-service/internal/auth"
+	"github.com/yuriiwanchev/banner-rotation-service/internal/ratelimit"
+)
+
+const defaultMaxRequestBodyBytes = 64 << 10
+
+var (
+	maxRequestBodyBytes int64 = defaultMaxRequestBodyBytes
+
+	rateLimitsMu     sync.Mutex
+	defaultRateLimit *ratelimit.Limit
+	rateLimits       map[string]ratelimit.Limit
+	rateLimiters     map[string]*ratelimit.Limiter
+	ipRateLimiter    *ratelimit.Limiter
+	trustedProxies   []netip.Prefix
+)
+
+// InitRequestLimits configures body size and rate limits. Rate limits are
+// keyed by operation name (the operationId of the route, or "grpc.<Method>"
+// for gRPC); operations without an entry use defaultLimit, and are not
+// limited at all when it is nil.
+func InitRequestLimits(maxBodyBytes int64, defaultLimit *ratelimit.Limit, limits map[string]ratelimit.Limit) {
+	rateLimitsMu.Lock()
+	defer rateLimitsMu.Unlock()
+
+	if maxBodyBytes > 0 {
+		maxRequestBodyBytes = maxBodyBytes
+	}
+	defaultRateLimit = defaultLimit
+	rateLimits = limits
+	rateLimiters = make(map[string]*ratelimit.Limiter)
+}
+
+// InitIPRateLimit limits the requests of each client IP before they are
+// authenticated, so that requests with missing or invalid credentials are
+// limited too. A nil limit disables it.
+func InitIPRateLimit(limit *ratelimit.Limit) {
+	rateLimitsMu.Lock()
+	defer rateLimitsMu.Unlock()
+
+	ipRateLimiter = nil
+	if limit != nil {
+		ipRateLimiter = ratelimit.NewLimiter(*limit)
+	}
+}
+
+// InitTrustedProxies makes the client IP of requests coming from the given
+// networks, such as the load balancer, be read from their X-Forwarded-For or
+// X-Real-IP header.
+func InitTrustedProxies(proxies []netip.Prefix) {
+	rateLimitsMu.Lock()
+	defer rateLimitsMu.Unlock()
+
+	trustedProxies = proxies
+}
+
+// ParseTrustedProxies parses a comma separated list of CIDRs or single IPs.
+func ParseTrustedProxies(value string) ([]netip.Prefix, error) {
+	var proxies []netip.Prefix
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if !strings.Contains(entry, "/") {
+			addr, err := netip.ParseAddr(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid proxy %q: %w", entry, err)
+			}
+			proxies = append(proxies, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy %q: %w", entry, err)
+		}
+		proxies = append(proxies, prefix.Masked())
+	}
+	return proxies, nil
+}
+
+// ClientIP returns the rate limiting key of the client connected from
+// remoteAddr. If remoteAddr is a trusted proxy, the client is the last
+// address in forwardedFor (the X-Forwarded-For values) that is not a trusted
+// proxy itself, or realIP (X-Real-IP) without forwardedFor.
+func ClientIP(remoteAddr string, forwardedFor []string, realIP string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+
+	rateLimitsMu.Lock()
+	proxies := trustedProxies
+	rateLimitsMu.Unlock()
+
+	if !isTrustedProxy(proxies, host) {
+		return "ip:" + host
+	}
+
+	var hops []string
+	for _, value := range forwardedFor {
+		hops = append(hops, strings.Split(value, ",")...)
+	}
+	if len(hops) == 0 && realIP != "" {
+		hops = []string{realIP}
+	}
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			// Anything left of a malformed hop may be forged.
+			break
+		}
+		host = hop.Unmap().String()
+		if !isTrustedProxy(proxies, host) {
+			break
+		}
+	}
+	return "ip:" + host
+}
+
+func isTrustedProxy(proxies []netip.Prefix, host string) bool {
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, proxy := range proxies {
+		if proxy.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Allow takes a token for client from the bucket of operation and returns a
+// *RateLimitError if there is none left.
+func Allow(operation, client string) error {
+	return allow(limiterFor(operation), client)
+}
+
+// AllowIP takes a token from the bucket of the client IP ip, before any
+// per-operation limit.
+func AllowIP(ip string) error {
+	rateLimitsMu.Lock()
+	limiter := ipRateLimiter
+	rateLimitsMu.Unlock()
+	return allow(limiter, ip)
+}
+
+func allow(limiter *ratelimit.Limiter, client string) error {
+	if limiter == nil {
+		return nil
+	}
+
+	if ok, retryAfter := limiter.Allow(client); !ok {
+		return &RateLimitError{RetryAfterSeconds: max(int(math.Ceil(retryAfter.Seconds())), 1)}
+	}
+	return nil
+}
+
+// RateLimitError is returned when a client exhausted its token bucket.
+type RateLimitError struct {
+	RetryAfterSeconds int
+}
+
+func (e *RateLimitError) Error() string {
+	return "Rate limit exceeded"
+}
+
+func limiterFor(operation string) *ratelimit.Limiter {
+	rateLimitsMu.Lock()
+	defer rateLimitsMu.Unlock()
+
+	if limiter, exists := rateLimiters[operation]; exists {
+		return limiter
+	}
+
+	limit, exists := rateLimits[operation]
+	if !exists {
+		if defaultRateLimit == nil {
+			return nil
+		}
+		limit = *defaultRateLimit
+	}
+
+	limiter := ratelimit.NewLimiter(limit)
+	rateLimiters[operation] = limiter
+	return limiter
+}
+
+// RateLimit limits requests to operation per API key, or per client IP for
+// unauthenticated requests.
+func RateLimit(operation string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := Allow(operation, clientKey(r)); err != nil {
+			rateLimitResponse(w, err)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// LimitIP limits requests per client IP. It runs before authentication.
+func LimitIP(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := AllowIP(clientIP(r)); err != nil {
+			rateLimitResponse(w, err)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func rateLimitResponse(w http.ResponseWriter, err error) {
+	var rateLimitErr *RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		w.Header().Set("Retry-After", strconv.Itoa(rateLimitErr.RetryAfterSeconds))
+	}
+	jsonResponse(w, http.StatusTooManyRequests, map[string]string{"error": err.Error()})
+}
+
+func clientKey(r *http.Request) string {
+	if principal, ok := auth.PrincipalFromContext(r.Context()); ok && principal.Subject != "anonymous" {
+		return principal.Subject
+	}
+	return clientIP(r)
+}
+
+func clientIP(r *http.Request) string {
+	return ClientIP(r.RemoteAddr, r.Header.Values("X-Forwarded-For"), r.Header.Get("X-Real-IP"))
+}
+
+// decodeJSON decodes a request body of bounded size into v, rejecting
+// fields that v does not declare.
+func decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return newRequestError(http.StatusRequestEntityTooLarge, "Request body too large")
+		}
+		return newRequestError(http.StatusBadRequest, "Invalid request payload")
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	m "github.com/yuriiwanchev/banner-rotation-service/internal/models"
+	"github.com/yuriiwanchev/banner-rotation-service/internal/ratelimit"
+)
+
+func TestDecodeJSON(t *testing.T) {
+	InitRequestLimits(48, nil, nil)
+	t.Cleanup(func() { InitRequestLimits(defaultMaxRequestBodyBytes, nil, nil) })
+
+	tests := []struct {
+		body   string
+		status int
+	}{
+		{`{"slotId":1,"userGroupId":2}`, 0},
+		{`{"slotId":1,"userGroupId":2,"extra":true}`, http.StatusBadRequest},
+		{`{"slotId":1,"userGroupId":2,"description":"` + strings.Repeat("x", 64) + `"}`, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		var request m.SelectBannerRequest
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/select-banner", strings.NewReader(tt.body))
+
+		err := decodeJSON(rr, req, &request)
+		status := 0
+		if err != nil {
+			status = err.(*RequestError).Status
+		}
+		if status != tt.status {
+			t.Errorf("decodeJSON(%s) status = %d, expected %d", tt.body, status, tt.status)
+		}
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	InitRequestLimits(0, nil, map[string]ratelimit.Limit{"createClick": {Rate: 1, Burst: 2}})
+	t.Cleanup(func() { InitRequestLimits(defaultMaxRequestBodyBytes, nil, nil) })
+
+	handler := RateLimit("createClick", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	codes := make([]int, 0, 3)
+	for i := 0; i < 3; i++ {
+		rr := httptest.NewRecorder()
+		handler(rr, httptest.NewRequest(http.MethodPost, "/v1/slots/1/banners/1/clicks", nil))
+		codes = append(codes, rr.Code)
+
+		if rr.Code == http.StatusTooManyRequests && rr.Header().Get("Retry-After") != "1" {
+			t.Errorf("Expected Retry-After of 1 second, got %q", rr.Header().Get("Retry-After"))
+		}
+	}
+
+	if codes[0] != http.StatusOK || codes[1] != http.StatusOK || codes[2] != http.StatusTooManyRequests {
+		t.Errorf("Unexpected status codes %v", codes)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	proxies, err := ParseTrustedProxies("10.0.0.0/8, 192.168.1.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	InitTrustedProxies(proxies)
+	t.Cleanup(func() { InitTrustedProxies(nil) })
+
+	tests := []struct {
+		remoteAddr   string
+		forwardedFor []string
+		realIP       string
+		expected     string
+	}{
+		{"203.0.113.5:1234", []string{"198.51.100.1"}, "", "ip:203.0.113.5"},
+		{"10.0.0.1:1234", nil, "", "ip:10.0.0.1"},
+		{"10.0.0.1:1234", []string{"198.51.100.1"}, "", "ip:198.51.100.1"},
+		{"10.0.0.1:1234", []string{"6.6.6.6, 198.51.100.1, 192.168.1.1"}, "", "ip:198.51.100.1"},
+		{"10.0.0.1:1234", []string{"6.6.6.6", "198.51.100.1"}, "", "ip:198.51.100.1"},
+		{"10.0.0.1:1234", []string{"garbage, 10.0.0.2"}, "", "ip:10.0.0.2"},
+		{"10.0.0.1:1234", nil, "198.51.100.2", "ip:198.51.100.2"},
+		{"[::ffff:10.0.0.1]:1234", []string{"198.51.100.1"}, "", "ip:198.51.100.1"},
+	}
+
+	for _, tt := range tests {
+		if ip := ClientIP(tt.remoteAddr, tt.forwardedFor, tt.realIP); ip != tt.expected {
+			t.Errorf("ClientIP(%q, %q, %q) = %q, expected %q", tt.remoteAddr, tt.forwardedFor, tt.realIP, ip, tt.expected)
+		}
+	}
+
+	if _, err := ParseTrustedProxies("10.0.0.0/33"); err == nil {
+		t.Error("Expected an error for an invalid CIDR")
+	}
+}
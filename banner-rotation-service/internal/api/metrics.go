@@ -0,0 +1,47 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/yuriiwanchev/banner-rotation-service/internal/repository"
+)
+
+const metricsNamespace = "banner_rotation"
+
+// MetricsHandler exposes the database connection pool statistics in the
+// Prometheus text format.
+func MetricsHandler(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	db := repository.GetDB()
+	if db == nil {
+		return
+	}
+	stats := db.Stats()
+
+	writeMetric(w, "db_max_open_connections", "gauge", "Maximum number of open connections to the database.",
+		float64(stats.MaxOpenConnections))
+	writeMetric(w, "db_open_connections", "gauge", "Number of established connections, in use and idle.",
+		float64(stats.OpenConnections))
+	writeMetric(w, "db_in_use_connections", "gauge", "Number of connections currently in use.",
+		float64(stats.InUse))
+	writeMetric(w, "db_idle_connections", "gauge", "Number of idle connections.",
+		float64(stats.Idle))
+	writeMetric(w, "db_wait_count_total", "counter", "Total number of connections waited for.",
+		float64(stats.WaitCount))
+	writeMetric(w, "db_wait_duration_seconds_total", "counter", "Total time blocked waiting for a new connection.",
+		stats.WaitDuration.Seconds())
+	writeMetric(w, "db_max_idle_closed_total", "counter", "Connections closed due to SetMaxIdleConns.",
+		float64(stats.MaxIdleClosed))
+	writeMetric(w, "db_max_idle_time_closed_total", "counter", "Connections closed due to SetConnMaxIdleTime.",
+		float64(stats.MaxIdleTimeClosed))
+	writeMetric(w, "db_max_lifetime_closed_total", "counter", "Connections closed due to SetConnMaxLifetime.",
+		float64(stats.MaxLifetimeClosed))
+}
+
+func writeMetric(w io.Writer, name, kind, help string, value float64) {
+	name = metricsNamespace + "_" + name
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %g\n", name, help, name, kind, name, value)
+}
@@ -0,0 +1,232 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	e "github.com/yuriiwanchev/banner-rotation-service/internal/entities"
+	m "github.com/yuriiwanchev/banner-rotation-service/internal/models"
+	"github.com/yuriiwanchev/banner-rotation-service/pkg/client"
+)
+
+type openAPIOperation struct {
+	OperationID  string `json:"operationId"`
+	RequiredRole e.Role `json:"x-required-role"`
+}
+
+type openAPIDocument struct {
+	Paths      map[string]map[string]openAPIOperation `json:"paths"`
+	Components struct {
+		Schemas map[string]struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"schemas"`
+	} `json:"components"`
+}
+
+// schemaTypes maps component schemas of the spec to the models they describe.
+var schemaTypes = map[string]reflect.Type{
+	"AddBannerRequest":        reflect.TypeOf(m.AddBannerRequest{}),
+	"RemoveBannerRequest":     reflect.TypeOf(m.RemoveBannerRequest{}),
+	"RecordClickRequest":      reflect.TypeOf(m.RecordClickRequest{}),
+	"RecordViewRequest":       reflect.TypeOf(m.RecordViewRequest{}),
+	"RecordConversionRequest": reflect.TypeOf(m.RecordConversionRequest{}),
+	"CreateConversionRequest": reflect.TypeOf(m.CreateConversionRequest{}),
+	"Slot":                    reflect.TypeOf(e.Slot{}),
+	"UpdateSlotRequest":       reflect.TypeOf(m.UpdateSlotRequest{}),
+	"TrafficStats":            reflect.TypeOf(m.TrafficStats{}),
+	"LiftReport":              reflect.TypeOf(m.LiftReport{}),
+	"SelectBannerRequest":     reflect.TypeOf(m.SelectBannerRequest{}),
+	"SelectBannerResponse":    reflect.TypeOf(m.SelectBannerResponse{}),
+	"AddSlotBannerRequest":    reflect.TypeOf(m.AddSlotBannerRequest{}),
+	"CreateSelectionRequest":  reflect.TypeOf(m.CreateSelectionRequest{}),
+	"CreateClickRequest":      reflect.TypeOf(m.CreateClickRequest{}),
+	"HealthResponse":          reflect.TypeOf(m.HealthResponse{}),
+	"BuildInfo":               reflect.TypeOf(m.BuildInfo{}),
+	"APIKey":                  reflect.TypeOf(e.APIKey{}),
+	"CreateAPIKeyRequest":     reflect.TypeOf(m.CreateAPIKeyRequest{}),
+	"CreateAPIKeyResponse":    reflect.TypeOf(m.CreateAPIKeyResponse{}),
+	"ReloadResponse":          reflect.TypeOf(m.ReloadResponse{}),
+	"SlotBanner":              reflect.TypeOf(e.SlotBanner{}),
+	"UpdateSlotBannerRequest": reflect.TypeOf(m.UpdateSlotBannerRequest{}),
+	"BannerDelivery":          reflect.TypeOf(m.BannerDelivery{}),
+	"Banner":                  reflect.TypeOf(e.Banner{}),
+	"Creative":                reflect.TypeOf(e.Creative{}),
+	"CreateBannerRequest":     reflect.TypeOf(m.CreateBannerRequest{}),
+}
+
+// clientMethods maps every operation of the spec to the method of the Go client
+// calling it. Probes, browser-facing endpoints and the deprecated aliases have
+// no client method.
+var clientMethods = map[string]string{
+	"healthz":          "",
+	"readyz":           "",
+	"openapi":          "",
+	"metrics":          "",
+	"clickRedirect":    "",
+	"pixelView":        "",
+	"pixelClick":       "",
+	"addBanner":        "",
+	"removeBanner":     "",
+	"recordClick":      "",
+	"selectBanner":     "",
+	"recordView":       "",
+	"recordConversion": "",
+	"version":          "Version",
+	"createBanner":     "CreateBanner",
+	"getBanner":        "GetBanner",
+	"getSlot":          "GetSlot",
+	"updateSlot":       "UpdateSlot",
+	"getSlotLift":      "GetSlotLift",
+	"listSlotBanners":  "ListSlotBanners",
+	"addSlotBanner":    "AddBanner",
+	"updateSlotBanner": "UpdateSlotBanner",
+	"removeSlotBanner": "RemoveBanner",
+	"getSlotDelivery":  "GetSlotDelivery",
+	"createSelection":  "SelectBanner",
+	"createClick":      "RecordClick",
+	"createView":       "RecordView",
+	"createConversion": "RecordConversion",
+	"createAPIKey":     "CreateAPIKey",
+	"listAPIKeys":      "ListAPIKeys",
+	"revokeAPIKey":     "RevokeAPIKey",
+	"reload":           "Reload",
+}
+
+func loadOpenAPIDocument(t *testing.T) openAPIDocument {
+	t.Helper()
+	var doc openAPIDocument
+	if err := json.Unmarshal(openAPISpec, &doc); err != nil {
+		t.Fatalf("openapi.json is not valid JSON: %v", err)
+	}
+	return doc
+}
+
+func TestOpenAPISpecMatchesRoutes(t *testing.T) {
+	doc := loadOpenAPIDocument(t)
+
+	var specRoutes []string
+	for path, operations := range doc.Paths {
+		for method, operation := range operations {
+			specRoutes = append(specRoutes, strings.Join([]string{
+				operation.OperationID, strings.ToUpper(method), path, string(operation.RequiredRole),
+			}, " "))
+		}
+	}
+
+	var handlerRoutes []string
+	for _, rt := range routes() {
+		handlerRoutes = append(handlerRoutes, strings.Join([]string{rt.name, rt.method, rt.path, string(rt.role)}, " "))
+	}
+
+	sort.Strings(specRoutes)
+	sort.Strings(handlerRoutes)
+	if !reflect.DeepEqual(specRoutes, handlerRoutes) {
+		t.Errorf("openapi.json paths and registered routes differ:\nspec:     %v\nhandlers: %v",
+			specRoutes, handlerRoutes)
+	}
+}
+
+func TestOpenAPISchemasMatchModels(t *testing.T) {
+	doc := loadOpenAPIDocument(t)
+
+	for name, schema := range doc.Components.Schemas {
+		if name == "Error" || schema.Properties == nil {
+			continue
+		}
+
+		typ, ok := schemaTypes[name]
+		if !ok {
+			t.Errorf("schema %s has no model registered in schemaTypes", name)
+			continue
+		}
+
+		var specFields []string
+		for field := range schema.Properties {
+			specFields = append(specFields, field)
+		}
+
+		modelFields := jsonFieldNames(typ)
+
+		sort.Strings(specFields)
+		sort.Strings(modelFields)
+		if !reflect.DeepEqual(specFields, modelFields) {
+			t.Errorf("schema %s properties %v do not match model fields %v", name, specFields, modelFields)
+		}
+	}
+
+	for name := range schemaTypes {
+		if _, ok := doc.Components.Schemas[name]; !ok {
+			t.Errorf("model %s is missing from openapi.json", name)
+		}
+	}
+}
+
+func TestOpenAPISpecMatchesClient(t *testing.T) {
+	doc := loadOpenAPIDocument(t)
+	clientType := reflect.TypeOf(&client.Client{})
+
+	operations := map[string]bool{}
+	for _, pathOperations := range doc.Paths {
+		for _, operation := range pathOperations {
+			operations[operation.OperationID] = true
+
+			method, ok := clientMethods[operation.OperationID]
+			if !ok {
+				t.Errorf("operation %s has no entry in clientMethods", operation.OperationID)
+				continue
+			}
+			if _, ok := clientType.MethodByName(method); method != "" && !ok {
+				t.Errorf("operation %s has no client method %s", operation.OperationID, method)
+			}
+		}
+	}
+
+	for operationID := range clientMethods {
+		if !operations[operationID] {
+			t.Errorf("clientMethods lists %s, which is missing from openapi.json", operationID)
+		}
+	}
+}
+
+func TestOpenAPIHandler(t *testing.T) {
+	rr := httptest.NewRecorder()
+	NewRouter().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rr.Code)
+	}
+	if rr.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("Unexpected content type %q", rr.Header().Get("Content-Type"))
+	}
+	if !json.Valid(rr.Body.Bytes()) {
+		t.Errorf("Served document is not valid JSON")
+	}
+}
+
+func jsonFieldNames(typ reflect.Type) []string {
+	var names []string
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			names = append(names, jsonFieldNames(field.Type)...)
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	e "github.com/yuriiwanchev/banner-rotation-service/internal/entities"
+	"github.com/yuriiwanchev/banner-rotation-service/internal/impression"
+)
+
+// transparentGIF is a 1x1 transparent GIF.
+var transparentGIF = []byte{
+	'G', 'I', 'F', '8', '9', 'a', 0x01, 0x00, 0x01, 0x00, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0xff, 0xff, 0xff, 0x21, 0xf9, 0x04, 0x01, 0x00, 0x00, 0x00, 0x00, 0x2c, 0x00, 0x00, 0x00, 0x00,
+	0x01, 0x00, 0x01, 0x00, 0x00, 0x02, 0x02, 0x44, 0x01, 0x00, 0x3b,
+}
+
+// PixelViewHandler handles GET /pixel/view: the placement rendered the
+// impression. If views are confirmed separately, this confirms it.
+func PixelViewHandler(w http.ResponseWriter, r *http.Request) {
+	imp, err := pixelImpression(r)
+	if err != nil {
+		pixelResponse(w, err)
+		return
+	}
+
+	if pendingViews != nil {
+		pixelResponse(w, ConfirmView(r.Context(), r.URL.Query().Get("impressionId")))
+		return
+	}
+
+	publishEvent(e.Event{
+		Type:        e.Render,
+		SlotID:      imp.SlotID,
+		BannerID:    imp.BannerID,
+		UserGroupID: imp.UserGroupID,
+	})
+	pixelResponse(w, nil)
+}
+
+// PixelClickHandler handles GET /pixel/click: the impression was clicked.
+func PixelClickHandler(w http.ResponseWriter, r *http.Request) {
+	imp, err := pixelImpression(r)
+	if err != nil {
+		pixelResponse(w, err)
+		return
+	}
+
+	pixelResponse(w, recordImpressionClick(r.Context(), imp))
+}
+
+// pixelImpression returns the impression of the impressionId query parameter.
+// The slotId, bannerId and userGroupId parameters are optional, but must match
+// the impression if present.
+func pixelImpression(r *http.Request) (impression.Impression, error) {
+	if impressionSigner == nil {
+		return impression.Impression{}, newRequestError(http.StatusNotFound, "Impression IDs are not enabled")
+	}
+	query := r.URL.Query()
+	imp, err := impressionSigner.Decode(query.Get("impressionId"))
+	if err != nil {
+		return imp, newRequestError(http.StatusBadRequest, "Invalid impression ID")
+	}
+
+	for name, want := range map[string]int{
+		"slotId":      int(imp.SlotID),
+		"bannerId":    int(imp.BannerID),
+		"userGroupId": int(imp.UserGroupID),
+	} {
+		if value := query.Get(name); value != "" && value != strconv.Itoa(want) {
+			return imp, newRequestError(http.StatusBadRequest, name+" does not match the impression")
+		}
+	}
+	return imp, nil
+}
+
+// recordImpressionClick records the first click on the impression unless it
+// has expired.
+func recordImpressionClick(ctx context.Context, imp impression.Impression) error {
+	if time.Since(imp.ShownAt) > impressionTTL || !clickedImpressions.add(imp) {
+		return nil
+	}
+	return recordClick(ctx, imp.SlotID, imp.BannerID, imp.UserGroupID, imp.Control)
+}
+
+// pixelResponse always sends the GIF, so that a failure does not show up as
+// a broken image; the status still tells it apart.
+func pixelResponse(w http.ResponseWriter, err error) {
+	status := http.StatusOK
+	if err != nil {
+		log.Printf("Pixel request failed: %v", err)
+		status = http.StatusInternalServerError
+		var requestErr *RequestError
+		if errors.As(err, &requestErr) {
+			status = requestErr.Status
+		}
+	}
+
+	w.Header().Set("Content-Type", "image/gif")
+	w.Header().Set("Cache-Control", "no-store, no-cache, must-revalidate, max-age=0")
+	w.Header().Set("Pragma", "no-cache")
+	w.Header().Set("Expires", "0")
+	w.WriteHeader(status)
+	w.Write(transparentGIF)
+}
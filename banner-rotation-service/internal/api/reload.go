@@ -0,0 +1,67 @@
+package api
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	m "github.com/yuriiwanchev/banner-rotation-service/internal/models"
+)
+
+var reloadMu sync.Mutex
+
+// Reload rebuilds the rotation state from the database, picking up slots and
+// banners changed by other replicas or by hand, and swaps it in at once.
+// Cached creatives are dropped, to be read again on the next selection.
+// Views and clicks recorded while the state is loaded are in the database
+// already and are picked up by the next reload.
+func Reload(ctx context.Context) (m.ReloadResponse, error) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	var response m.ReloadResponse
+
+	slots, err := loadSlots(ctx)
+	if err != nil {
+		return response, err
+	}
+	banditService.Replace(slots)
+	banners.clear()
+
+	response.Slots = len(slots)
+	for _, slot := range slots {
+		response.Banners += len(slot.Banners)
+	}
+	return response, nil
+}
+
+// StartResync reloads the rotation state every interval until ctx is done.
+func StartResync(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := Reload(ctx); err != nil {
+					log.Printf("Failed to resync rotation state from db: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+func ReloadHandler(w http.ResponseWriter, r *http.Request) {
+	response, err := Reload(r.Context())
+	if err != nil {
+		errorResponse(w, storageError(err, "Failed to reload rotation state from db"))
+		return
+	}
+
+	jsonResponse(w, http.StatusOK, response)
+}
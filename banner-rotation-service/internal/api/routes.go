@@ -0,0 +1,141 @@
+package api
+
+import (
+	"net/http"
+
+	e "github.com/yuriiwanchev/banner-rotation-service/internal/entities"
+)
+
+type route struct {
+	name    string
+	method  string
+	path    string
+	role    e.Role
+	handler http.HandlerFunc
+}
+
+// routes lists every endpoint of the service with its operationId and the role
+// required to call it; an empty role means the endpoint is public. The list is
+// kept in sync with openapi.json by TestOpenAPISpecMatchesRoutes.
+func routes() []route {
+	return append(currentRoutes(), deprecatedRoutes()...)
+}
+
+func currentRoutes() []route {
+	return []route{
+		{"healthz", http.MethodGet, "/healthz", "", HealthzHandler},
+		{"readyz", http.MethodGet, "/readyz", "", ReadyzHandler},
+		{"version", http.MethodGet, "/version", "", VersionHandler},
+		{"openapi", http.MethodGet, "/openapi.json", "", OpenAPIHandler},
+		{"metrics", http.MethodGet, "/metrics", "", MetricsHandler},
+		// Tracking endpoints are public, as browsers call them without a key,
+		// and trust the signed impression ID instead.
+		{
+			"clickRedirect", http.MethodGet, "/click/{impressionId}", "",
+			RequireReady(RateLimit("clickRedirect", ClickRedirectHandler)),
+		},
+		{"pixelView", http.MethodGet, "/pixel/view", "", RequireReady(RateLimit("pixelView", PixelViewHandler))},
+		{"pixelClick", http.MethodGet, "/pixel/click", "", RequireReady(RateLimit("pixelClick", PixelClickHandler))},
+
+		{"createBanner", http.MethodPost, "/v1/banners", e.RoleAdmin, CreateBannerHandler},
+		{"getBanner", http.MethodGet, "/v1/banners/{bannerId}", e.RoleAdmin, GetBannerHandler},
+		{"getSlot", http.MethodGet, "/v1/slots/{slotId}", e.RoleAdmin, GetSlotHandler},
+		{"updateSlot", http.MethodPut, "/v1/slots/{slotId}", e.RoleAdmin, UpdateSlotHandler},
+		{"addSlotBanner", http.MethodPost, "/v1/slots/{slotId}/banners", e.RoleAdmin, AddSlotBannerHandler},
+		{"listSlotBanners", http.MethodGet, "/v1/slots/{slotId}/banners", e.RoleAdmin, ListSlotBannersHandler},
+		{
+			"updateSlotBanner", http.MethodPut, "/v1/slots/{slotId}/banners/{bannerId}", e.RoleAdmin,
+			UpdateSlotBannerHandler,
+		},
+		{"getSlotDelivery", http.MethodGet, "/v1/slots/{slotId}/delivery", e.RoleAdmin, GetDeliveryHandler},
+		{"getSlotLift", http.MethodGet, "/v1/slots/{slotId}/lift", e.RoleAdmin, GetLiftHandler},
+		{
+			"removeSlotBanner", http.MethodDelete, "/v1/slots/{slotId}/banners/{bannerId}", e.RoleAdmin,
+			RemoveSlotBannerHandler,
+		},
+		{
+			"createClick", http.MethodPost, "/v1/slots/{slotId}/banners/{bannerId}/clicks", e.RoleServing,
+			CreateClickHandler,
+		},
+		{
+			"createConversion", http.MethodPost, "/v1/slots/{slotId}/banners/{bannerId}/conversions", e.RoleServing,
+			CreateConversionHandler,
+		},
+		{
+			"createSelection", http.MethodPost, "/v1/slots/{slotId}/selections", e.RoleServing,
+			CreateSelectionHandler,
+		},
+		{
+			"createView", http.MethodPost, "/v1/impressions/{impressionId}/views", e.RoleServing,
+			CreateViewHandler,
+		},
+
+		{"createAPIKey", http.MethodPost, "/v1/admin/api-keys", e.RoleAdmin, CreateAPIKeyHandler},
+		{"listAPIKeys", http.MethodGet, "/v1/admin/api-keys", e.RoleAdmin, ListAPIKeysHandler},
+		{"revokeAPIKey", http.MethodDelete, "/v1/admin/api-keys/{keyId}", e.RoleAdmin, RevokeAPIKeyHandler},
+		{"reload", http.MethodPost, "/admin/reload", e.RoleAdmin, ReloadHandler},
+	}
+}
+
+// deprecatedRoutes are the verb-style aliases kept for existing clients. They
+// are documented with POST, but accept any method as they always did.
+func deprecatedRoutes() []route {
+	return []route{
+		{
+			"addBanner", http.MethodPost, "/add-banner", e.RoleAdmin,
+			deprecated("/v1/slots/{slotId}/banners", AddBannerHandler),
+		},
+		{
+			"removeBanner", http.MethodPost, "/remove-banner", e.RoleAdmin,
+			deprecated("/v1/slots/{slotId}/banners/{bannerId}", RemoveBannerHandler),
+		},
+		{
+			"recordClick", http.MethodPost, "/record-click", e.RoleServing,
+			deprecated("/v1/slots/{slotId}/banners/{bannerId}/clicks", RecordClickHandler),
+		},
+		{
+			"recordConversion", http.MethodPost, "/record-conversion", e.RoleServing,
+			deprecated("/v1/slots/{slotId}/banners/{bannerId}/conversions", RecordConversionHandler),
+		},
+		{
+			"selectBanner", http.MethodPost, "/select-banner", e.RoleServing,
+			deprecated("/v1/slots/{slotId}/selections", SelectBannerHandler),
+		},
+		{
+			"recordView", http.MethodPost, "/record-view", e.RoleServing,
+			deprecated("/v1/impressions/{impressionId}/views", RecordViewHandler),
+		},
+	}
+}
+
+// NewRouter registers every endpoint of the service on a new ServeMux.
+// Requests with a wrong method on a current path get 405 from the mux itself.
+// Protected endpoints are only served once the service is ready, since both
+// they and the API key lookup need the database, and are rate limited per
+// caller. Public probes are never limited; public tracking endpoints wrap
+// their handlers themselves.
+func NewRouter() *http.ServeMux {
+	mux := http.NewServeMux()
+	for _, rt := range currentRoutes() {
+		mux.HandleFunc(rt.method+" "+rt.path, protect(rt))
+	}
+	for _, rt := range deprecatedRoutes() {
+		mux.HandleFunc(rt.path, protect(rt))
+	}
+	return mux
+}
+
+func protect(rt route) http.HandlerFunc {
+	if rt.role == "" {
+		return rt.handler
+	}
+	return RequireReady(LimitIP(RequireRole(rt.role, RateLimit(rt.name, rt.handler))))
+}
+
+func deprecated(successor string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", "<"+successor+">; rel=\"successor-version\"")
+		next(w, r)
+	}
+}
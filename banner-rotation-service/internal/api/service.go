@@ -0,0 +1,500 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"time"
+
+	e "github.com/yuriiwanchev/banner-rotation-service/internal/entities"
+	"github.com/yuriiwanchev/banner-rotation-service/internal/impression"
+	"github.com/yuriiwanchev/banner-rotation-service/internal/logic/bandit"
+	m "github.com/yuriiwanchev/banner-rotation-service/internal/models"
+	"github.com/yuriiwanchev/banner-rotation-service/internal/repository"
+)
+
+// RequestError is a failure caused by the request itself or by the state of
+// the service. Status is the HTTP status it maps to; other transports
+// translate it to their own codes.
+type RequestError struct {
+	Status  int
+	Message string
+}
+
+func (e *RequestError) Error() string {
+	return e.Message
+}
+
+func newRequestError(status int, message string) *RequestError {
+	return &RequestError{Status: status, Message: message}
+}
+
+// storageError logs a repository failure and reports it as 503 if the query
+// timed out, so that callers may retry, or as 500 with message otherwise.
+func storageError(err error, message string) *RequestError {
+	log.Println(err)
+	if errors.Is(err, repository.ErrQueryTimeout) {
+		return newRequestError(http.StatusServiceUnavailable, "Database did not respond in time")
+	}
+	return newRequestError(http.StatusInternalServerError, message)
+}
+
+func AddBanner(ctx context.Context, request m.AddBannerRequest) error {
+	if request.SlotID == 0 || request.BannerID == 0 {
+		return newRequestError(http.StatusBadRequest, "SlotID and BannerID are required")
+	}
+
+	banditService.AddBanner(request.SlotID, request.BannerID)
+
+	if err := slotBannersRepository.AddBannerToSlot(ctx, request.SlotID, request.BannerID); err != nil {
+		return storageError(err, "Failed to add banner to slot to db")
+	}
+
+	userGroupIDs, err := userGroupRepository.GetAllUserGroupsIDs(ctx)
+	if err != nil {
+		return storageError(err, "Failed to get userGroupIds from db")
+	}
+
+	if err := statisticRepository.CreateStartStatisticsForBannerInSlot(ctx, request.SlotID,
+		request.BannerID, userGroupIDs); err != nil {
+		return storageError(err, "Failed to CreateStartStatisticsForBannerInSlot")
+	}
+
+	return nil
+}
+
+func RemoveBanner(ctx context.Context, request m.RemoveBannerRequest) error {
+	if request.SlotID == 0 || request.BannerID == 0 {
+		return newRequestError(http.StatusBadRequest, "SlotID and BannerID are required")
+	}
+
+	err := banditService.RemoveBanner(request.SlotID, request.BannerID)
+	if err != nil {
+		return newRequestError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := slotBannersRepository.RemoveBannerFromSlot(ctx, request.SlotID, request.BannerID); err != nil {
+		return storageError(err, "Failed to add banner to db")
+	}
+
+	return nil
+}
+
+// CreateBanner stores a new banner with its creative, if any.
+func CreateBanner(ctx context.Context, request m.CreateBannerRequest) (e.Banner, error) {
+	banner := e.Banner{Description: request.Description, Creative: request.Creative}
+	if banner.Creative != nil {
+		if err := validateCreative(banner.Creative); err != nil {
+			return banner, err
+		}
+	}
+
+	id, err := bannerRepository.CreateBanner(ctx, &banner)
+	if err != nil {
+		return banner, storageError(err, "Failed to create banner in db")
+	}
+	banner.ID = id
+	banners.set(id, &banner)
+
+	return banner, nil
+}
+
+func GetBanner(ctx context.Context, id e.BannerID) (*e.Banner, error) {
+	banner, err := bannerRepository.GetBannerByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, newRequestError(http.StatusNotFound, "Banner not found")
+		}
+		return nil, storageError(err, "Failed to get banner from db")
+	}
+	return banner, nil
+}
+
+const maxAltTextLength = 500
+
+func validateCreative(creative *e.Creative) *RequestError {
+	if !isAbsoluteURL(creative.ImageURL) || !isAbsoluteURL(creative.TargetURL) {
+		return newRequestError(http.StatusBadRequest, "ImageURL and TargetURL must be absolute http or https URLs")
+	}
+	if len(creative.AltText) > maxAltTextLength {
+		return newRequestError(http.StatusBadRequest,
+			fmt.Sprintf("AltText must not be longer than %d bytes", maxAltTextLength))
+	}
+	if creative.Width <= 0 || creative.Height <= 0 {
+		return newRequestError(http.StatusBadRequest, "Width and Height must be positive")
+	}
+	switch creative.Format {
+	case e.FormatPNG, e.FormatJPEG, e.FormatGIF, e.FormatWebP, e.FormatSVG:
+	default:
+		return newRequestError(http.StatusBadRequest, "Format must be one of png, jpeg, gif, webp, svg")
+	}
+	return nil
+}
+
+func isAbsoluteURL(value string) bool {
+	u, err := url.Parse(value)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// UpdateSlotBanner replaces the flight dates and status of a banner in a slot.
+func UpdateSlotBanner(ctx context.Context, slotBanner e.SlotBanner) (e.SlotBanner, error) {
+	if slotBanner.SlotID == 0 || slotBanner.BannerID == 0 {
+		return slotBanner, newRequestError(http.StatusBadRequest, "SlotID and BannerID are required")
+	}
+	if slotBanner.Status == "" {
+		slotBanner.Status = e.BannerActive
+	}
+	if slotBanner.Status != e.BannerActive && slotBanner.Status != e.BannerPaused {
+		return slotBanner, newRequestError(http.StatusBadRequest, "Status must be active or paused")
+	}
+	if slotBanner.StartsAt != nil && slotBanner.EndsAt != nil && !slotBanner.EndsAt.After(*slotBanner.StartsAt) {
+		return slotBanner, newRequestError(http.StatusBadRequest, "EndsAt must be after StartsAt")
+	}
+	if slotBanner.MinDailyImpressions < 0 || slotBanner.MaxDailyImpressions < 0 {
+		return slotBanner, newRequestError(http.StatusBadRequest, "Daily impressions must not be negative")
+	}
+	if slotBanner.MaxDailyImpressions > 0 && slotBanner.MinDailyImpressions > slotBanner.MaxDailyImpressions {
+		return slotBanner, newRequestError(http.StatusBadRequest,
+			"MinDailyImpressions must not exceed MaxDailyImpressions")
+	}
+
+	if err := slotBannersRepository.UpdateSlotBanner(ctx, &slotBanner); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return slotBanner, newRequestError(http.StatusNotFound, "Banner is not in the slot")
+		}
+		return slotBanner, storageError(err, "Failed to update banner in slot in db")
+	}
+
+	// A banner missing from the bandit is picked up with its schedule on the next reload.
+	if err := banditService.SetSchedule(slotBanner.SlotID, slotBanner.BannerID, scheduleOf(&slotBanner)); err != nil {
+		log.Println(err)
+	}
+
+	return slotBanner, nil
+}
+
+func ListSlotBanners(ctx context.Context, slotID e.SlotID) ([]*e.SlotBanner, error) {
+	if slotID == 0 {
+		return nil, newRequestError(http.StatusBadRequest, "SlotID is required")
+	}
+
+	slotBanners, err := slotBannersRepository.GetSlotBanners(ctx, slotID)
+	if err != nil {
+		return nil, storageError(err, "Failed to get banners of slot from db")
+	}
+	if slotBanners == nil {
+		slotBanners = []*e.SlotBanner{}
+	}
+	return slotBanners, nil
+}
+
+// GetDelivery reports how far the banners of a slot are towards their daily
+// impression goals. The counts come from the database, so they cover every
+// replica.
+func GetDelivery(ctx context.Context, slotID e.SlotID) ([]*m.BannerDelivery, error) {
+	if slotID == 0 {
+		return nil, newRequestError(http.StatusBadRequest, "SlotID is required")
+	}
+
+	slotBanners, err := slotBannersRepository.GetSlotBanners(ctx, slotID)
+	if err != nil {
+		return nil, storageError(err, "Failed to get banners of slot from db")
+	}
+
+	day := bandit.Day(time.Now())
+	impressions, err := statisticRepository.GetDailyImpressionsForSlot(ctx, slotID, day)
+	if err != nil {
+		return nil, storageError(err, "Failed to get daily impressions from db")
+	}
+
+	deliveries := make([]*m.BannerDelivery, 0, len(slotBanners))
+	for _, slotBanner := range slotBanners {
+		delivery := &m.BannerDelivery{
+			BannerID:            slotBanner.BannerID,
+			Day:                 day.Format(time.DateOnly),
+			MinDailyImpressions: slotBanner.MinDailyImpressions,
+			MaxDailyImpressions: slotBanner.MaxDailyImpressions,
+			Impressions:         impressions[slotBanner.BannerID],
+		}
+		delivery.MissingImpressions = max(delivery.MinDailyImpressions-delivery.Impressions, 0)
+		if delivery.MaxDailyImpressions > 0 {
+			remaining := max(delivery.MaxDailyImpressions-delivery.Impressions, 0)
+			delivery.RemainingImpressions = &remaining
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, nil
+}
+
+// GetLift compares the CTR of the traffic of a slot served by the bandit with
+// that of its control group.
+func GetLift(ctx context.Context, slotID e.SlotID) (*m.LiftReport, error) {
+	slot, err := GetSlot(ctx, slotID)
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := statisticRepository.GetStatisticsForSlot(ctx, slotID)
+	if err != nil {
+		return nil, storageError(err, "Failed to get statistics from db")
+	}
+
+	report := &m.LiftReport{SlotID: slotID, HoldoutPercent: slot.HoldoutPercent}
+	for _, stat := range stats {
+		report.Bandit.Views += stat.Views - stat.ControlViews
+		report.Bandit.Clicks += stat.Clicks - stat.ControlClicks
+		report.Control.Views += stat.ControlViews
+		report.Control.Clicks += stat.ControlClicks
+	}
+	served, control := &report.Bandit, &report.Control
+	if served.Views > 0 {
+		served.CTR = float64(served.Clicks) / float64(served.Views)
+	}
+	if control.Views > 0 {
+		control.CTR = float64(control.Clicks) / float64(control.Views)
+	}
+	if served.Views == 0 || control.Views == 0 {
+		return report, nil
+	}
+
+	if control.CTR > 0 {
+		lift := served.CTR/control.CTR - 1
+		report.Lift = &lift
+	}
+	// Two-proportion z-test with the pooled CTR.
+	pooled := float64(served.Clicks+control.Clicks) / float64(served.Views+control.Views)
+	standardError := math.Sqrt(pooled * (1 - pooled) * (1/float64(served.Views) + 1/float64(control.Views)))
+	if standardError > 0 {
+		zScore := (served.CTR - control.CTR) / standardError
+		report.ZScore = &zScore
+	}
+	return report, nil
+}
+
+func scheduleOf(slotBanner *e.SlotBanner) bandit.Schedule {
+	schedule := bandit.Schedule{
+		Paused:              slotBanner.Status == e.BannerPaused,
+		MinDailyImpressions: slotBanner.MinDailyImpressions,
+		MaxDailyImpressions: slotBanner.MaxDailyImpressions,
+	}
+	if slotBanner.StartsAt != nil {
+		schedule.StartsAt = *slotBanner.StartsAt
+	}
+	if slotBanner.EndsAt != nil {
+		schedule.EndsAt = *slotBanner.EndsAt
+	}
+	return schedule
+}
+
+// RecordClick counts a click. Only clicks carrying the impression ID of a
+// control group selection are counted as control clicks, and only the first
+// click carrying an impression ID is counted.
+func RecordClick(ctx context.Context, request m.RecordClickRequest) error {
+	if request.SlotID == 0 || request.BannerID == 0 || request.UserGroupID == 0 {
+		return newRequestError(http.StatusBadRequest, "SlotID, BannerID, and UserGroup are required")
+	}
+
+	if request.ImpressionID == "" {
+		return recordClick(ctx, request.SlotID, request.BannerID, request.UserGroupID, false)
+	}
+
+	imp, err := clickedImpression(request)
+	if err != nil {
+		return err
+	}
+	return recordImpressionClick(ctx, imp)
+}
+
+func recordClick(ctx context.Context, slotID e.SlotID, bannerID e.BannerID, groupID e.UserGroupID,
+	control bool,
+) error {
+	err := banditService.RecordClick(slotID, bannerID, groupID)
+	if err != nil {
+		return newRequestError(http.StatusBadRequest, err.Error())
+	}
+
+	publishEvent(e.Event{
+		Type:        e.Click,
+		SlotID:      slotID,
+		BannerID:    bannerID,
+		UserGroupID: groupID,
+		Control:     control,
+	})
+
+	if control {
+		err = statisticRepository.IncrementControlClick(ctx, slotID, bannerID, groupID)
+	} else {
+		err = statisticRepository.IncrementClick(ctx, slotID, bannerID, groupID)
+	}
+	if err != nil {
+		return storageError(err, "Failed to record click")
+	}
+
+	return nil
+}
+
+// RecordConversion counts a conversion of a banner, worth value if it has one.
+func RecordConversion(ctx context.Context, request m.RecordConversionRequest) error {
+	if request.SlotID == 0 || request.BannerID == 0 || request.UserGroupID == 0 {
+		return newRequestError(http.StatusBadRequest, "SlotID, BannerID, and UserGroup are required")
+	}
+	if request.Value < 0 {
+		return newRequestError(http.StatusBadRequest, "value must not be negative")
+	}
+
+	err := banditService.RecordConversion(request.SlotID, request.BannerID, request.UserGroupID, request.Value)
+	if err != nil {
+		return newRequestError(http.StatusBadRequest, err.Error())
+	}
+
+	publishEvent(e.Event{
+		Type:        e.Conversion,
+		SlotID:      request.SlotID,
+		BannerID:    request.BannerID,
+		UserGroupID: request.UserGroupID,
+		Value:       request.Value,
+	})
+
+	err = statisticRepository.IncrementConversion(ctx, request.SlotID, request.BannerID, request.UserGroupID,
+		request.Value)
+	if err != nil {
+		return storageError(err, "Failed to record conversion")
+	}
+
+	return nil
+}
+
+// GetSlot returns a slot with its optimization goal.
+func GetSlot(ctx context.Context, slotID e.SlotID) (*e.Slot, error) {
+	slot, err := slotRepository.GetSlotByID(ctx, slotID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, newRequestError(http.StatusNotFound, "Slot not found")
+		}
+		return nil, storageError(err, "Failed to get slot from db")
+	}
+	return slot, nil
+}
+
+// UpdateSlot replaces the optimization goal, rotation strategy and holdout of
+// a slot. Settings missing from request are reset rather than kept: an empty
+// goal is GoalCTR, an empty strategy StrategyUCB1 and a zero holdout disables
+// the control group.
+func UpdateSlot(ctx context.Context, slotID e.SlotID, request m.UpdateSlotRequest) (*e.Slot, error) {
+	slot := &e.Slot{
+		ID:             slotID,
+		Goal:           request.Goal,
+		Strategy:       request.Strategy,
+		HoldoutPercent: request.HoldoutPercent,
+	}
+	switch slot.Goal {
+	case "":
+		slot.Goal = e.GoalCTR
+	case e.GoalCTR, e.GoalConversions, e.GoalRevenue:
+	default:
+		return nil, newRequestError(http.StatusBadRequest, "goal must be ctr, conversions or revenue")
+	}
+	switch slot.Strategy {
+	case "":
+		slot.Strategy = e.StrategyUCB1
+	case e.StrategyUCB1, e.StrategyUCBV:
+	default:
+		return nil, newRequestError(http.StatusBadRequest, "strategy must be ucb1 or ucb-v")
+	}
+	if slot.HoldoutPercent < 0 || slot.HoldoutPercent > 100 {
+		return nil, newRequestError(http.StatusBadRequest, "holdoutPercent must be between 0 and 100")
+	}
+
+	if err := slotRepository.UpdateSlotRotation(ctx, slot); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, newRequestError(http.StatusNotFound, "Slot not found")
+		}
+		return nil, storageError(err, "Failed to update slot")
+	}
+	banditService.SetGoal(slotID, slot.Goal)
+	banditService.SetStrategy(slotID, slot.Strategy)
+	banditService.SetHoldout(slotID, slot.HoldoutPercent)
+
+	return GetSlot(ctx, slotID)
+}
+
+func SelectBanner(ctx context.Context, request m.SelectBannerRequest) (m.SelectBannerResponse, error) {
+	var response m.SelectBannerResponse
+
+	if request.SlotID == 0 || request.UserGroupID == 0 {
+		return response, newRequestError(http.StatusBadRequest, "SlotID and UserGroup are required")
+	}
+
+	capped := cappedBanners(request.SlotID, request.UserID)
+	selection := banditService.Select(request.SlotID, request.UserGroupID, pendingViews != nil, capped...)
+	response.BannerID, response.Control = selection.BannerID, selection.Control
+	if response.BannerID == 0 {
+		return response, newRequestError(http.StatusNotFound, "No banner available for the given slot and user group")
+	}
+
+	var imp impression.Impression
+	imp, response.ImpressionID = issueImpression(request.SlotID, request.UserGroupID, selection)
+
+	// The banner was selected already, so a failure to read its creative
+	// does not fail the selection.
+	if banner, err := banners.get(ctx, response.BannerID); err != nil {
+		log.Printf("Failed to get creative of banner %d: %v", response.BannerID, err)
+	} else if banner != nil {
+		response.Creative = banner.Creative
+	}
+
+	// The view is counted, also towards the frequency cap, once it is confirmed.
+	if pendingViews != nil {
+		pendingViews.add(imp, request.UserID)
+		return response, nil
+	}
+	recordUserView(request.SlotID, response.BannerID, request.UserID)
+
+	publishEvent(e.Event{
+		Type:        e.View,
+		SlotID:      request.SlotID,
+		BannerID:    response.BannerID,
+		UserGroupID: request.UserGroupID,
+		UserID:      request.UserID,
+		Control:     response.Control,
+	})
+
+	err := incrementView(ctx, request.SlotID, response.BannerID, request.UserGroupID, response.Control)
+	if err != nil {
+		return response, storageError(err, "Failed to record view")
+	}
+
+	return response, nil
+}
+
+// incrementView counts a view in the statistics, also as a control view if
+// the banner was held out.
+func incrementView(ctx context.Context, slotID e.SlotID, bannerID e.BannerID, groupID e.UserGroupID,
+	control bool,
+) error {
+	if control {
+		return statisticRepository.IncrementControlView(ctx, slotID, bannerID, groupID)
+	}
+	return statisticRepository.IncrementView(ctx, slotID, bannerID, groupID)
+}
+
+func publishEvent(event e.Event) {
+	producer := kafkaProducer.Load()
+	if producer == nil {
+		return
+	}
+
+	// The user ID is only needed by the other replicas for frequency capping,
+	// so it is not published outside cluster mode.
+	if replicator.Load() == nil {
+		event.UserID = ""
+	}
+	event.Source = instanceID
+	event.Time = time.Now()
+	producer.PublishEvent(event)
+}
@@ -0,0 +1,327 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	e "github.com/yuriiwanchev/banner-rotation-service/internal/entities"
+	m "github.com/yuriiwanchev/banner-rotation-service/internal/models"
+)
+
+// CreateBannerHandler handles POST /v1/banners.
+func CreateBannerHandler(w http.ResponseWriter, r *http.Request) {
+	var request m.CreateBannerRequest
+	if err := decodeJSON(w, r, &request); err != nil {
+		errorResponse(w, err)
+		return
+	}
+
+	banner, err := CreateBanner(r.Context(), request)
+	if err != nil {
+		errorResponse(w, err)
+		return
+	}
+
+	jsonResponse(w, http.StatusCreated, banner)
+}
+
+// GetBannerHandler handles GET /v1/banners/{bannerId}.
+func GetBannerHandler(w http.ResponseWriter, r *http.Request) {
+	bannerID, err := pathID(r, "bannerId")
+	if err != nil {
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	banner, err := GetBanner(r.Context(), e.BannerID(bannerID))
+	if err != nil {
+		errorResponse(w, err)
+		return
+	}
+
+	jsonResponse(w, http.StatusOK, banner)
+}
+
+// GetSlotHandler handles GET /v1/slots/{slotId}.
+func GetSlotHandler(w http.ResponseWriter, r *http.Request) {
+	slotID, err := pathID(r, "slotId")
+	if err != nil {
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	slot, err := GetSlot(r.Context(), e.SlotID(slotID))
+	if err != nil {
+		errorResponse(w, err)
+		return
+	}
+
+	jsonResponse(w, http.StatusOK, slot)
+}
+
+// UpdateSlotHandler handles PUT /v1/slots/{slotId}.
+func UpdateSlotHandler(w http.ResponseWriter, r *http.Request) {
+	slotID, err := pathID(r, "slotId")
+	if err != nil {
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	var request m.UpdateSlotRequest
+	if err := decodeJSON(w, r, &request); err != nil {
+		errorResponse(w, err)
+		return
+	}
+
+	slot, err := UpdateSlot(r.Context(), e.SlotID(slotID), request)
+	if err != nil {
+		errorResponse(w, err)
+		return
+	}
+
+	jsonResponse(w, http.StatusOK, slot)
+}
+
+// AddSlotBannerHandler handles POST /v1/slots/{slotId}/banners.
+func AddSlotBannerHandler(w http.ResponseWriter, r *http.Request) {
+	slotID, err := pathID(r, "slotId")
+	if err != nil {
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	var request m.AddSlotBannerRequest
+	if err := decodeJSON(w, r, &request); err != nil {
+		errorResponse(w, err)
+		return
+	}
+
+	err = AddBanner(r.Context(), m.AddBannerRequest{
+		SlotID:   e.SlotID(slotID),
+		BannerID: request.BannerID,
+	})
+	if err != nil {
+		errorResponse(w, err)
+		return
+	}
+
+	jsonResponse(w, http.StatusOK, nil)
+}
+
+// RemoveSlotBannerHandler handles DELETE /v1/slots/{slotId}/banners/{bannerId}.
+func RemoveSlotBannerHandler(w http.ResponseWriter, r *http.Request) {
+	slotID, err := pathID(r, "slotId")
+	if err != nil {
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	bannerID, err := pathID(r, "bannerId")
+	if err != nil {
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	err = RemoveBanner(r.Context(), m.RemoveBannerRequest{
+		SlotID:   e.SlotID(slotID),
+		BannerID: e.BannerID(bannerID),
+	})
+	if err != nil {
+		errorResponse(w, err)
+		return
+	}
+
+	jsonResponse(w, http.StatusOK, nil)
+}
+
+// ListSlotBannersHandler handles GET /v1/slots/{slotId}/banners.
+func ListSlotBannersHandler(w http.ResponseWriter, r *http.Request) {
+	slotID, err := pathID(r, "slotId")
+	if err != nil {
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	slotBanners, err := ListSlotBanners(r.Context(), e.SlotID(slotID))
+	if err != nil {
+		errorResponse(w, err)
+		return
+	}
+
+	jsonResponse(w, http.StatusOK, slotBanners)
+}
+
+// UpdateSlotBannerHandler handles PUT /v1/slots/{slotId}/banners/{bannerId}.
+func UpdateSlotBannerHandler(w http.ResponseWriter, r *http.Request) {
+	slotID, err := pathID(r, "slotId")
+	if err != nil {
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	bannerID, err := pathID(r, "bannerId")
+	if err != nil {
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	var request m.UpdateSlotBannerRequest
+	if err := decodeJSON(w, r, &request); err != nil {
+		errorResponse(w, err)
+		return
+	}
+
+	slotBanner, err := UpdateSlotBanner(r.Context(), e.SlotBanner{
+		SlotID:              e.SlotID(slotID),
+		BannerID:            e.BannerID(bannerID),
+		StartsAt:            request.StartsAt,
+		EndsAt:              request.EndsAt,
+		Status:              request.Status,
+		MinDailyImpressions: request.MinDailyImpressions,
+		MaxDailyImpressions: request.MaxDailyImpressions,
+	})
+	if err != nil {
+		errorResponse(w, err)
+		return
+	}
+
+	jsonResponse(w, http.StatusOK, slotBanner)
+}
+
+// GetDeliveryHandler handles GET /v1/slots/{slotId}/delivery.
+func GetDeliveryHandler(w http.ResponseWriter, r *http.Request) {
+	slotID, err := pathID(r, "slotId")
+	if err != nil {
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	deliveries, err := GetDelivery(r.Context(), e.SlotID(slotID))
+	if err != nil {
+		errorResponse(w, err)
+		return
+	}
+
+	jsonResponse(w, http.StatusOK, deliveries)
+}
+
+// GetLiftHandler handles GET /v1/slots/{slotId}/lift.
+func GetLiftHandler(w http.ResponseWriter, r *http.Request) {
+	slotID, err := pathID(r, "slotId")
+	if err != nil {
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	report, err := GetLift(r.Context(), e.SlotID(slotID))
+	if err != nil {
+		errorResponse(w, err)
+		return
+	}
+
+	jsonResponse(w, http.StatusOK, report)
+}
+
+// CreateClickHandler handles POST /v1/slots/{slotId}/banners/{bannerId}/clicks.
+func CreateClickHandler(w http.ResponseWriter, r *http.Request) {
+	slotID, err := pathID(r, "slotId")
+	if err != nil {
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	bannerID, err := pathID(r, "bannerId")
+	if err != nil {
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	var request m.CreateClickRequest
+	if err := decodeJSON(w, r, &request); err != nil {
+		errorResponse(w, err)
+		return
+	}
+
+	err = RecordClick(r.Context(), m.RecordClickRequest{
+		SlotID:       e.SlotID(slotID),
+		BannerID:     e.BannerID(bannerID),
+		UserGroupID:  request.UserGroupID,
+		ImpressionID: request.ImpressionID,
+	})
+	if err != nil {
+		errorResponse(w, err)
+		return
+	}
+
+	jsonResponse(w, http.StatusOK, nil)
+}
+
+// CreateConversionHandler handles POST /v1/slots/{slotId}/banners/{bannerId}/conversions.
+func CreateConversionHandler(w http.ResponseWriter, r *http.Request) {
+	slotID, err := pathID(r, "slotId")
+	if err != nil {
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	bannerID, err := pathID(r, "bannerId")
+	if err != nil {
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	var request m.CreateConversionRequest
+	if err := decodeJSON(w, r, &request); err != nil {
+		errorResponse(w, err)
+		return
+	}
+
+	err = RecordConversion(r.Context(), m.RecordConversionRequest{
+		SlotID:      e.SlotID(slotID),
+		BannerID:    e.BannerID(bannerID),
+		UserGroupID: request.UserGroupID,
+		Value:       request.Value,
+	})
+	if err != nil {
+		errorResponse(w, err)
+		return
+	}
+
+	jsonResponse(w, http.StatusOK, nil)
+}
+
+// CreateSelectionHandler handles POST /v1/slots/{slotId}/selections.
+func CreateSelectionHandler(w http.ResponseWriter, r *http.Request) {
+	slotID, err := pathID(r, "slotId")
+	if err != nil {
+		jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	var request m.CreateSelectionRequest
+	if err := decodeJSON(w, r, &request); err != nil {
+		errorResponse(w, err)
+		return
+	}
+
+	response, err := SelectBanner(r.Context(), m.SelectBannerRequest{
+		SlotID:      e.SlotID(slotID),
+		UserGroupID: request.UserGroupID,
+		UserID:      request.UserID,
+	})
+	if err != nil {
+		errorResponse(w, err)
+		return
+	}
+
+	jsonResponse(w, http.StatusOK, response)
+}
+
+func pathID(r *http.Request, name string) (int, error) {
+	id, err := strconv.Atoi(r.PathValue(name))
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("invalid %s in path", name)
+	}
+	return id, nil
+}
@@ -0,0 +1,158 @@
+package api
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	e "github.com/yuriiwanchev/banner-rotation-service/internal/entities"
+	"github.com/yuriiwanchev/banner-rotation-service/internal/impression"
+	m "github.com/yuriiwanchev/banner-rotation-service/internal/models"
+)
+
+// pendingViews holds the selections awaiting confirmation of their view; nil
+// unless views are confirmed separately.
+var pendingViews *pendingImpressions
+
+// InitViewConfirmation makes selections count as views only once confirmed
+// with /record-view or the view pixel, within timeout of the selection. A
+// timeout of 0 counts views on selection. Impression IDs must be enabled with
+// InitImpressions.
+func InitViewConfirmation(timeout time.Duration) {
+	if timeout <= 0 {
+		pendingViews = nil
+		return
+	}
+	pendingViews = &pendingImpressions{
+		timeout:     timeout,
+		impressions: make(map[uint64]*pendingImpression),
+		lastSweep:   time.Now(),
+	}
+}
+
+// pendingImpressions are keyed by the nonce of the impression. Confirmed ones
+// are kept until they expire, so that confirming twice counts one view.
+type pendingImpressions struct {
+	timeout time.Duration
+
+	mu          sync.Mutex
+	impressions map[uint64]*pendingImpression
+	lastSweep   time.Time
+}
+
+type pendingImpression struct {
+	impression.Impression
+	userID    string
+	confirmed bool
+}
+
+func (p *pendingImpressions) add(imp impression.Impression, userID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.impressions[imp.Nonce] = &pendingImpression{Impression: imp, userID: userID}
+
+	if now := time.Now(); now.Sub(p.lastSweep) >= p.timeout/4 {
+		p.sweep(now)
+	}
+}
+
+// confirm marks the impression as confirmed. It returns false if it was
+// confirmed already, and the ID of the user it was selected for if known.
+func (p *pendingImpressions) confirm(imp impression.Impression) (first bool, userID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pending, exists := p.impressions[imp.Nonce]
+	if !exists {
+		// Selected by another replica or before a restart.
+		p.impressions[imp.Nonce] = &pendingImpression{Impression: imp, confirmed: true}
+		return true, ""
+	}
+	if pending.confirmed {
+		return false, pending.userID
+	}
+	pending.confirmed = true
+	return true, pending.userID
+}
+
+// sweep forgets expired impressions, dropping the pending views of those
+// never confirmed. The caller must hold p.mu.
+func (p *pendingImpressions) sweep(now time.Time) {
+	for nonce, pending := range p.impressions {
+		if now.Sub(pending.ShownAt) <= p.timeout {
+			continue
+		}
+		if !pending.confirmed {
+			if err := banditService.ExpireView(pending.SlotID, pending.BannerID, pending.UserGroupID); err != nil {
+				log.Println(err)
+			}
+		}
+		delete(p.impressions, nonce)
+	}
+	p.lastSweep = now
+}
+
+// ConfirmView counts the view of a pending impression.
+func ConfirmView(ctx context.Context, impressionID string) error {
+	if pendingViews == nil || impressionSigner == nil {
+		return newRequestError(http.StatusConflict, "Views are counted on selection and need no confirmation")
+	}
+	imp, err := impressionSigner.Decode(impressionID)
+	if err != nil {
+		return newRequestError(http.StatusBadRequest, "Invalid impression ID")
+	}
+	if time.Since(imp.ShownAt) > pendingViews.timeout {
+		return newRequestError(http.StatusGone, "Impression has expired")
+	}
+
+	first, userID := pendingViews.confirm(imp)
+	if !first {
+		return nil
+	}
+
+	if err := banditService.ConfirmView(imp.SlotID, imp.BannerID, imp.UserGroupID); err != nil {
+		log.Println(err)
+	}
+	recordUserView(imp.SlotID, imp.BannerID, userID)
+
+	publishEvent(e.Event{
+		Type:        e.View,
+		SlotID:      imp.SlotID,
+		BannerID:    imp.BannerID,
+		UserGroupID: imp.UserGroupID,
+		UserID:      userID,
+		Control:     imp.Control,
+	})
+
+	if err := incrementView(ctx, imp.SlotID, imp.BannerID, imp.UserGroupID, imp.Control); err != nil {
+		return storageError(err, "Failed to record view")
+	}
+	return nil
+}
+
+// CreateViewHandler handles POST /v1/impressions/{impressionId}/views.
+func CreateViewHandler(w http.ResponseWriter, r *http.Request) {
+	if err := ConfirmView(r.Context(), r.PathValue("impressionId")); err != nil {
+		errorResponse(w, err)
+		return
+	}
+	jsonResponse(w, http.StatusOK, nil)
+}
+
+// RecordViewHandler handles POST /record-view.
+func RecordViewHandler(w http.ResponseWriter, r *http.Request) {
+	var request m.RecordViewRequest
+	if err := decodeJSON(w, r, &request); err != nil {
+		errorResponse(w, err)
+		return
+	}
+
+	if err := ConfirmView(r.Context(), request.ImpressionID); err != nil {
+		errorResponse(w, err)
+		return
+	}
+	jsonResponse(w, http.StatusOK, nil)
+}
@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	e "github.com/yuriiwanchev/banner-rotation-service/internal/entities"
+)
+
+const apiKeyPrefix = "brs_"
+
+var (
+	ErrUnauthenticated = errors.New("missing or invalid credentials")
+	ErrForbidden       = errors.New("insufficient role")
+)
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	Subject string
+	Role    e.Role
+}
+
+// Allows reports whether the principal may call an endpoint requiring role.
+// Admins may call everything.
+func (p *Principal) Allows(role e.Role) bool {
+	return p.Role == e.RoleAdmin || p.Role == role
+}
+
+// KeyStore looks up stored API keys. Unknown or revoked keys are reported
+// as sql.ErrNoRows.
+type KeyStore interface {
+	GetAPIKeyByHash(ctx context.Context, keyHash string) (*e.APIKey, error)
+}
+
+// Authenticator verifies API keys against hashes in the key store and
+// HMAC-signed JWTs against a shared secret.
+type Authenticator struct {
+	keys          KeyStore
+	jwtSecret     []byte
+	bootstrapHash string
+}
+
+// NewAuthenticator creates an authenticator. An empty jwtSecret disables JWTs.
+// bootstrapKey, if set, is accepted as an admin key without being stored, so
+// that the first real keys can be created through the admin API.
+func NewAuthenticator(keys KeyStore, jwtSecret []byte, bootstrapKey string) *Authenticator {
+	a := &Authenticator{
+		keys:      keys,
+		jwtSecret: jwtSecret,
+	}
+	if bootstrapKey != "" {
+		a.bootstrapHash = HashKey(bootstrapKey)
+	}
+	return a
+}
+
+// Authenticate resolves the caller behind token. Failures of the key store
+// other than an unknown key are returned as is rather than as ErrUnauthenticated.
+func (a *Authenticator) Authenticate(ctx context.Context, token string) (*Principal, error) {
+	if token == "" {
+		return nil, ErrUnauthenticated
+	}
+
+	if strings.Count(token, ".") == 2 {
+		if len(a.jwtSecret) == 0 {
+			return nil, ErrUnauthenticated
+		}
+		claims, err := VerifyJWT(token, a.jwtSecret)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrUnauthenticated, err)
+		}
+		return &Principal{Subject: "jwt:" + claims.Subject, Role: claims.Role}, nil
+	}
+
+	hash := HashKey(token)
+	if a.bootstrapHash != "" && subtle.ConstantTimeCompare([]byte(hash), []byte(a.bootstrapHash)) == 1 {
+		return &Principal{Subject: "bootstrap", Role: e.RoleAdmin}, nil
+	}
+
+	key, err := a.keys.GetAPIKeyByHash(ctx, hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUnauthenticated
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up api key: %w", err)
+	}
+	return &Principal{Subject: fmt.Sprintf("key:%d", key.ID), Role: key.Role}, nil
+}
+
+// HashKey returns the hex encoded SHA-256 of an API key. Keys are random
+// 256-bit tokens, so a fast hash is sufficient to store them.
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func GenerateKey() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return apiKeyPrefix + hex.EncodeToString(buf), nil
+}
+
+func ValidRole(role e.Role) bool {
+	return role == e.RoleServing || role == e.RoleAdmin
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok
+}
+
+// TokenFromHeader extracts the credential from an Authorization header value.
+func TokenFromHeader(header string) string {
+	if token, ok := strings.CutPrefix(header, "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	return ""
+}
@@ -0,0 +1,119 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	e "github.com/yuriiwanchev/banner-rotation-service/internal/entities"
+)
+
+type fakeKeyStore map[string]*e.APIKey
+
+func (s fakeKeyStore) GetAPIKeyByHash(_ context.Context, keyHash string) (*e.APIKey, error) {
+	key, ok := s[keyHash]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return key, nil
+}
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := fakeKeyStore{HashKey(key): {ID: 7, Name: "frontend", Role: e.RoleServing}}
+	a := NewAuthenticator(store, nil, "")
+
+	principal, err := a.Authenticate(context.Background(), key)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if principal.Role != e.RoleServing || principal.Subject != "key:7" {
+		t.Errorf("Unexpected principal %+v", principal)
+	}
+	if principal.Allows(e.RoleAdmin) {
+		t.Errorf("Serving key must not be allowed admin endpoints")
+	}
+
+	if _, err := a.Authenticate(context.Background(), key+"x"); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("Expected ErrUnauthenticated for unknown key, got %v", err)
+	}
+}
+
+func TestAuthenticateBootstrapKey(t *testing.T) {
+	a := NewAuthenticator(fakeKeyStore{}, nil, "bootstrap-secret")
+
+	principal, err := a.Authenticate(context.Background(), "bootstrap-secret")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !principal.Allows(e.RoleAdmin) || !principal.Allows(e.RoleServing) {
+		t.Errorf("Bootstrap key must be admin, got %+v", principal)
+	}
+}
+
+func TestAuthenticateJWT(t *testing.T) {
+	secret := []byte("test-secret")
+	a := NewAuthenticator(fakeKeyStore{}, secret, "")
+
+	token, err := SignJWT(Claims{Subject: "ad-frontend", Role: e.RoleServing}, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	principal, err := a.Authenticate(context.Background(), token)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if principal.Role != e.RoleServing || principal.Subject != "jwt:ad-frontend" {
+		t.Errorf("Unexpected principal %+v", principal)
+	}
+
+	otherSecret, _ := SignJWT(Claims{Subject: "ad-frontend", Role: e.RoleAdmin}, []byte("other"))
+	if _, err := a.Authenticate(context.Background(), otherSecret); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("Expected token signed with another secret to be rejected, got %v", err)
+	}
+
+	parts := strings.Split(token, ".")
+	escalated, _ := SignJWT(Claims{Subject: "ad-frontend", Role: e.RoleAdmin}, secret)
+	tampered := strings.Split(escalated, ".")[0] + "." + strings.Split(escalated, ".")[1] + "." + parts[2]
+	if _, err := a.Authenticate(context.Background(), tampered); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("Expected tampered token to be rejected, got %v", err)
+	}
+
+	expired, _ := SignJWT(Claims{Subject: "ad-frontend", Role: e.RoleServing,
+		ExpiresAt: time.Now().Add(-time.Minute).Unix()}, secret)
+	if _, err := a.Authenticate(context.Background(), expired); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("Expected expired token to be rejected, got %v", err)
+	}
+}
+
+func TestAuthenticateJWTDisabled(t *testing.T) {
+	token, _ := SignJWT(Claims{Subject: "x", Role: e.RoleAdmin}, []byte(""))
+	a := NewAuthenticator(fakeKeyStore{}, nil, "")
+
+	if _, err := a.Authenticate(context.Background(), token); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("Expected JWT to be rejected without a secret, got %v", err)
+	}
+}
+
+type failingKeyStore struct{ err error }
+
+func (s failingKeyStore) GetAPIKeyByHash(context.Context, string) (*e.APIKey, error) {
+	return nil, s.err
+}
+
+func TestAuthenticateStoreFailure(t *testing.T) {
+	storeErr := errors.New("connection reset")
+	a := NewAuthenticator(failingKeyStore{storeErr}, nil, "")
+
+	_, err := a.Authenticate(context.Background(), "brs_key")
+	if !errors.Is(err, storeErr) || errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("Expected the store error to be propagated, got %v", err)
+	}
+}
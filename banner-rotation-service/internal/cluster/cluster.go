@@ -0,0 +1,162 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	e "github.com/yuriiwanchev/banner-rotation-service/internal/entities"
+)
+
+// Publisher sends events to every instance of the cluster.
+type Publisher interface {
+	PublishEvent(event e.Event) error
+}
+
+// Subscriber delivers the events published by all instances, including the
+// subscribing one.
+type Subscriber interface {
+	Next(ctx context.Context) (e.Event, error)
+}
+
+// Applier is the local state that replicated events are applied to.
+type Applier interface {
+	RecordView(slotID e.SlotID, bannerID e.BannerID, groupID e.UserGroupID) error
+	RecordClick(slotID e.SlotID, bannerID e.BannerID, groupID e.UserGroupID) error
+}
+
+// UserViewRecorder is optionally implemented by an Applier to also learn which
+// user a replicated view was for, e.g. for frequency capping.
+type UserViewRecorder interface {
+	RecordUserView(slotID e.SlotID, bannerID e.BannerID, userID string)
+}
+
+// ConversionRecorder is optionally implemented by an Applier to also apply
+// replicated conversions.
+type ConversionRecorder interface {
+	RecordConversion(slotID e.SlotID, bannerID e.BannerID, groupID e.UserGroupID, value float64) error
+}
+
+// Replicator keeps the in-memory bandit of an instance in step with the other
+// replicas by applying the views and clicks they publish.
+//
+// Staleness is bounded by heartbeats: every instance publishes one each
+// quarter of maxStaleness, and since it also reads its own, the time of the
+// newest event read tells how far behind the stream the instance is. Once it
+// lags more than maxStaleness, Healthy fails so that the instance can be taken
+// out of rotation until it catches up. Heartbeats go to the same topic as the
+// other events, as only there they measure its lag; consumers of the topic
+// must skip them.
+type Replicator struct {
+	instanceID   string
+	target       Applier
+	publisher    Publisher
+	events       Subscriber
+	maxStaleness time.Duration
+
+	newestEvent atomic.Int64 // UnixNano of the newest event read
+}
+
+func NewReplicator(instanceID string, target Applier, publisher Publisher, events Subscriber,
+	maxStaleness time.Duration,
+) *Replicator {
+	r := &Replicator{
+		instanceID:   instanceID,
+		target:       target,
+		publisher:    publisher,
+		events:       events,
+		maxStaleness: maxStaleness,
+	}
+	r.newestEvent.Store(time.Now().UnixNano())
+	return r
+}
+
+// Run applies events until ctx is done.
+func (r *Replicator) Run(ctx context.Context) error {
+	go r.sendHeartbeats(ctx)
+
+	for {
+		event, err := r.events.Next(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			log.Printf("cluster: failed to read event: %v", err)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+
+		r.observe(event.Time)
+		if event.Source == r.instanceID {
+			continue
+		}
+		if err := r.apply(event); err != nil {
+			log.Printf("cluster: failed to apply %s event from %q: %v", event.Type, event.Source, err)
+		}
+	}
+}
+
+func (r *Replicator) apply(event e.Event) error {
+	switch event.Type {
+	case e.View:
+		if recorder, ok := r.target.(UserViewRecorder); ok && event.UserID != "" {
+			recorder.RecordUserView(event.SlotID, event.BannerID, event.UserID)
+		}
+		return r.target.RecordView(event.SlotID, event.BannerID, event.UserGroupID)
+	case e.Click:
+		return r.target.RecordClick(event.SlotID, event.BannerID, event.UserGroupID)
+	case e.Conversion:
+		if recorder, ok := r.target.(ConversionRecorder); ok {
+			return recorder.RecordConversion(event.SlotID, event.BannerID, event.UserGroupID, event.Value)
+		}
+		return nil
+	default:
+		return nil
+	}
+}
+
+func (r *Replicator) observe(eventTime time.Time) {
+	t := eventTime.UnixNano()
+	for {
+		newest := r.newestEvent.Load()
+		if t <= newest || r.newestEvent.CompareAndSwap(newest, t) {
+			return
+		}
+	}
+}
+
+func (r *Replicator) sendHeartbeats(ctx context.Context) {
+	ticker := time.NewTicker(r.maxStaleness / 4)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			err := r.publisher.PublishEvent(e.Event{Type: e.Heartbeat, Source: r.instanceID, Time: now})
+			if err != nil {
+				log.Printf("cluster: failed to publish heartbeat: %v", err)
+			}
+		}
+	}
+}
+
+// Staleness is the age of the newest event read from the stream.
+func (r *Replicator) Staleness() time.Duration {
+	return time.Since(time.Unix(0, r.newestEvent.Load()))
+}
+
+// Healthy reports an error if the instance lags the stream more than allowed.
+func (r *Replicator) Healthy() error {
+	if staleness := r.Staleness(); staleness > r.maxStaleness {
+		return fmt.Errorf("event stream is %v behind", staleness.Round(time.Millisecond))
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	e "github.com/yuriiwanchev/banner-rotation-service/internal/entities"
+	"github.com/yuriiwanchev/banner-rotation-service/internal/logic/bandit"
+)
+
+const (
+	testSlot    = e.SlotID(1)
+	testGroup   = e.UserGroupID(1)
+	testBanners = 3
+)
+
+type instance struct {
+	id         string
+	bandit     *bandit.MultiArmedBandit
+	replicator *Replicator
+}
+
+func newInstance(id string, bus *MemoryBus, maxStaleness time.Duration) *instance {
+	mab := bandit.NewMultiArmedBandit(make(map[e.SlotID]*bandit.Slot))
+	for i := 1; i <= testBanners; i++ {
+		mab.AddBanner(testSlot, e.BannerID(i))
+	}
+	return &instance{
+		id:         id,
+		bandit:     mab,
+		replicator: NewReplicator(id, mab, bus, bus.Subscribe(), maxStaleness),
+	}
+}
+
+// serve selects a banner and clicks every third one, publishing the events
+// the way the API does.
+func (in *instance) serve(bus *MemoryBus, n int) {
+	for i := 0; i < n; i++ {
+		bannerID := in.bandit.SelectBanner(testSlot, testGroup)
+		bus.PublishEvent(e.Event{
+			Type: e.View, SlotID: testSlot, BannerID: bannerID, UserGroupID: testGroup,
+			Source: in.id, Time: time.Now(),
+		})
+
+		if i%3 == 0 {
+			in.bandit.RecordClick(testSlot, bannerID, testGroup)
+			bus.PublishEvent(e.Event{
+				Type: e.Click, SlotID: testSlot, BannerID: bannerID, UserGroupID: testGroup,
+				Source: in.id, Time: time.Now(),
+			})
+		}
+	}
+}
+
+func (in *instance) stats() map[e.BannerID]bandit.GroupStats {
+	stats := make(map[e.BannerID]bandit.GroupStats)
+	for i := 1; i <= testBanners; i++ {
+		stats[e.BannerID(i)], _ = in.bandit.GetStats(testSlot, e.BannerID(i), testGroup)
+	}
+	return stats
+}
+
+func TestReplicasConverge(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := NewMemoryBus()
+	maxStaleness := 200 * time.Millisecond
+
+	instances := make([]*instance, 3)
+	for i := range instances {
+		instances[i] = newInstance(fmt.Sprintf("replica-%d", i), bus, maxStaleness)
+		go instances[i].replicator.Run(ctx)
+	}
+
+	var wg sync.WaitGroup
+	for i, in := range instances {
+		wg.Add(1)
+		go func(in *instance, n int) {
+			defer wg.Done()
+			in.serve(bus, n)
+		}(in, 100*(i+1))
+	}
+	wg.Wait()
+
+	const totalViews = 100 + 200 + 300
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		converged := true
+		for _, in := range instances {
+			views := 0
+			for _, stats := range in.stats() {
+				views += stats.Views
+			}
+			if views != totalViews || fmt.Sprint(in.stats()) != fmt.Sprint(instances[0].stats()) {
+				converged = false
+			}
+		}
+		if converged {
+			break
+		}
+		if time.Now().After(deadline) {
+			for _, in := range instances {
+				t.Logf("%s: %v", in.id, in.stats())
+			}
+			t.Fatal("Replicas did not converge")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	time.Sleep(maxStaleness)
+	for _, in := range instances {
+		if err := in.replicator.Healthy(); err != nil {
+			t.Errorf("%s: expected heartbeats to keep staleness bounded, got %v", in.id, err)
+		}
+	}
+}
+
+func TestStalenessExceeded(t *testing.T) {
+	bus := NewMemoryBus()
+	maxStaleness := 50 * time.Millisecond
+	in := newInstance("replica", bus, maxStaleness)
+
+	// The replicator is not running, so it never reads a heartbeat.
+	time.Sleep(2 * maxStaleness)
+
+	if err := in.replicator.Healthy(); err == nil {
+		t.Error("Expected an instance behind the stream to be unhealthy")
+	}
+}
@@ -0,0 +1,57 @@
+package cluster
+
+import (
+	"context"
+	"sync"
+
+	e "github.com/yuriiwanchev/banner-rotation-service/internal/entities"
+)
+
+const memoryBusBuffer = 1024
+
+var _ Publisher = (*MemoryBus)(nil)
+
+// MemoryBus is an in-process event stream delivering every event to every
+// subscriber, for running several instances in one process.
+type MemoryBus struct {
+	mu          sync.RWMutex
+	subscribers []chan e.Event
+}
+
+func NewMemoryBus() *MemoryBus {
+	return &MemoryBus{}
+}
+
+// Subscribe returns a subscriber receiving the events published from now on.
+func (b *MemoryBus) Subscribe() Subscriber {
+	ch := make(chan e.Event, memoryBusBuffer)
+
+	b.mu.Lock()
+	b.subscribers = append(b.subscribers, ch)
+	b.mu.Unlock()
+
+	return memorySubscriber(ch)
+}
+
+// PublishEvent blocks while a subscriber's buffer is full.
+func (b *MemoryBus) PublishEvent(event e.Event) error {
+	b.mu.RLock()
+	subscribers := b.subscribers
+	b.mu.RUnlock()
+
+	for _, ch := range subscribers {
+		ch <- event
+	}
+	return nil
+}
+
+type memorySubscriber chan e.Event
+
+func (s memorySubscriber) Next(ctx context.Context) (e.Event, error) {
+	select {
+	case <-ctx.Done():
+		return e.Event{}, ctx.Err()
+	case event := <-s:
+		return event, nil
+	}
+}
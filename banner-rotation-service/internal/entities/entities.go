@@ -1,5 +1,7 @@
 package entities
 
+import "time"
+
 type (
 	SlotID      int
 	BannerID    int
@@ -7,13 +9,61 @@ type (
 )
 
 type Slot struct {
-	ID          SlotID `json:"id"`
-	Description string `json:"description"`
+	ID          SlotID           `json:"id"`
+	Description string           `json:"description"`
+	Goal        OptimizationGoal `json:"goal"`
+	Strategy    RotationStrategy `json:"strategy"`
+	// HoldoutPercent is the share of selections, from 0 to 100, served at
+	// random as a control group for measuring the lift of the bandit.
+	HoldoutPercent int `json:"holdoutPercent"`
 }
 
+// OptimizationGoal is the reward the bandit maximizes per impression in a slot.
+type OptimizationGoal string
+
+const (
+	GoalCTR         OptimizationGoal = "ctr"
+	GoalConversions OptimizationGoal = "conversions"
+	GoalRevenue     OptimizationGoal = "revenue"
+)
+
+// RotationStrategy is the algorithm the bandit picks banners of a slot with.
+type RotationStrategy string
+
+const (
+	// StrategyUCB1 ranks banners by their mean reward plus a bonus shrinking
+	// with their impressions. It suits click and conversion rates.
+	StrategyUCB1 RotationStrategy = "ucb1"
+	// StrategyUCBV also takes the variance of the rewards into account, which
+	// suits real-valued rewards such as revenue.
+	StrategyUCBV RotationStrategy = "ucb-v"
+)
+
 type Banner struct {
-	ID          BannerID `json:"id"`
-	Description string   `json:"description"`
+	ID          BannerID  `json:"id"`
+	Description string    `json:"description"`
+	Creative    *Creative `json:"creative,omitempty"`
+}
+
+type CreativeFormat string
+
+const (
+	FormatPNG  CreativeFormat = "png"
+	FormatJPEG CreativeFormat = "jpeg"
+	FormatGIF  CreativeFormat = "gif"
+	FormatWebP CreativeFormat = "webp"
+	FormatSVG  CreativeFormat = "svg"
+)
+
+// Creative is what a frontend needs to render a banner: the image to show and
+// where a click on it leads.
+type Creative struct {
+	ImageURL  string         `json:"imageUrl"`
+	AltText   string         `json:"altText"`
+	TargetURL string         `json:"targetUrl"`
+	Width     int            `json:"width"`
+	Height    int            `json:"height"`
+	Format    CreativeFormat `json:"format"`
 }
 
 type UserGroup struct {
@@ -21,11 +71,40 @@ type UserGroup struct {
 	Description string      `json:"description"`
 }
 
+type BannerStatus string
+
+const (
+	BannerActive BannerStatus = "active"
+	BannerPaused BannerStatus = "paused"
+)
+
+// SlotBanner is the membership of a banner in a slot. A banner is shown only
+// while it is active and within its flight dates, if set. Non-zero daily
+// impression goals are paced per UTC day.
+type SlotBanner struct {
+	SlotID              SlotID       `json:"slotId"`
+	BannerID            BannerID     `json:"bannerId"`
+	StartsAt            *time.Time   `json:"startsAt,omitempty"`
+	EndsAt              *time.Time   `json:"endsAt,omitempty"`
+	Status              BannerStatus `json:"status"`
+	MinDailyImpressions int          `json:"minDailyImpressions"`
+	MaxDailyImpressions int          `json:"maxDailyImpressions"`
+}
+
 type Event struct {
 	Type        EventType   `json:"type"`
 	SlotID      SlotID      `json:"slotId"`
 	BannerID    BannerID    `json:"bannerId"`
 	UserGroupID UserGroupID `json:"userGroupId"`
+	// UserID is the opaque ID of the user shown the banner, if the client sent one.
+	UserID string `json:"userId,omitempty"`
+	// Value is the monetary value of a conversion, if any.
+	Value float64 `json:"value,omitempty"`
+	// Control is set for views and clicks of the holdout control group.
+	Control bool `json:"control,omitempty"`
+	// Source is the ID of the instance that published the event, if it runs in cluster mode.
+	Source string    `json:"source,omitempty"`
+	Time   time.Time `json:"time"`
 }
 
 type EventType string
@@ -33,6 +112,13 @@ type EventType string
 const (
 	Click EventType = "Click"
 	View  EventType = "View"
+	// Conversion is a purchase, signup or other goal reached after a click.
+	Conversion EventType = "Conversion"
+	// Render confirms that a selected banner was displayed, e.g. by a tracking pixel.
+	Render EventType = "Render"
+	// Heartbeat is published periodically by instances in cluster mode to
+	// measure how far behind the event stream they are.
+	Heartbeat EventType = "Heartbeat"
 )
 
 type Statistics struct {
@@ -42,4 +128,29 @@ type Statistics struct {
 	UserGroupID UserGroupID `json:"userGroupId"`
 	Clicks      int         `json:"clicks"`
 	Views       int         `json:"views"`
+	Conversions int         `json:"conversions"`
+	Revenue     float64     `json:"revenue"`
+	// RevenueSquares is the sum of the squared conversion values.
+	RevenueSquares float64 `json:"revenueSquares"`
+	// ControlViews and ControlClicks are the part of Views and Clicks served
+	// to the holdout control group.
+	ControlViews  int `json:"controlViews"`
+	ControlClicks int `json:"controlClicks"`
+}
+
+type APIKeyID int
+
+type Role string
+
+const (
+	RoleServing Role = "serving"
+	RoleAdmin   Role = "admin"
+)
+
+type APIKey struct {
+	ID        APIKeyID   `json:"id"`
+	Name      string     `json:"name"`
+	Role      Role       `json:"role"`
+	CreatedAt time.Time  `json:"createdAt"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
 }
@@ -0,0 +1,307 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/yuriiwanchev/banner-rotation-service/internal/api"
+	"github.com/yuriiwanchev/banner-rotation-service/internal/auth"
+	e "github.com/yuriiwanchev/banner-rotation-service/internal/entities"
+	m "github.com/yuriiwanchev/banner-rotation-service/internal/models"
+	"github.com/yuriiwanchev/banner-rotation-service/pkg/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Server implements pb.BannerRotationServer on top of the same operations
+// the HTTP handlers use, so both transports share the bandit and repositories.
+type Server struct {
+	pb.UnimplementedBannerRotationServer
+}
+
+// ready reports whether the rotation algorithm has been warm-started; tests
+// replace it.
+var ready = api.Ready
+
+// methodRoles mirrors the roles of the corresponding HTTP routes.
+var methodRoles = map[string]e.Role{
+	pb.BannerRotation_SelectBanner_FullMethodName: e.RoleServing,
+	pb.BannerRotation_RecordView_FullMethodName:   e.RoleServing,
+	pb.BannerRotation_RecordClick_FullMethodName:  e.RoleServing,
+	pb.BannerRotation_RecordClicks_FullMethodName: e.RoleServing,
+	pb.BannerRotation_AddBanner_FullMethodName:    e.RoleAdmin,
+	pb.BannerRotation_RemoveBanner_FullMethodName: e.RoleAdmin,
+}
+
+func NewGRPCServer(opts ...grpc.ServerOption) *grpc.Server {
+	opts = append(opts,
+		grpc.ChainUnaryInterceptor(requireReadyUnary, limitIPUnary, authorizeUnary, rateLimitUnary),
+		grpc.ChainStreamInterceptor(requireReadyStream, limitIPStream, authorizeStream),
+	)
+	server := grpc.NewServer(opts...)
+	pb.RegisterBannerRotationServer(server, &Server{})
+	return server
+}
+
+func (s *Server) SelectBanner(ctx context.Context, req *pb.SelectBannerRequest) (*pb.SelectBannerResponse, error) {
+	response, err := api.SelectBanner(ctx, m.SelectBannerRequest{
+		SlotID:      e.SlotID(req.GetSlotId()),
+		UserGroupID: e.UserGroupID(req.GetUserGroupId()),
+		UserID:      req.GetUserId(),
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.SelectBannerResponse{
+		BannerId:     int64(response.BannerID),
+		Creative:     creative(response.Creative),
+		ImpressionId: response.ImpressionID,
+		Control:      response.Control,
+	}, nil
+}
+
+func (s *Server) RecordView(ctx context.Context, req *pb.RecordViewRequest) (*pb.RecordViewResponse, error) {
+	if err := api.ConfirmView(ctx, req.GetImpressionId()); err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.RecordViewResponse{}, nil
+}
+
+func (s *Server) RecordClick(ctx context.Context, req *pb.RecordClickRequest) (*pb.RecordClickResponse, error) {
+	if err := api.RecordClick(ctx, recordClickRequest(req)); err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.RecordClickResponse{}, nil
+}
+
+// RecordClicks applies every click of the stream independently: a click that
+// fails validation or exceeds the caller's rate limit is counted as rejected
+// and does not abort the stream.
+func (s *Server) RecordClicks(stream pb.BannerRotation_RecordClicksServer) error {
+	response := &pb.RecordClicksResponse{}
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return stream.SendAndClose(response)
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := api.Allow(rateLimitOperation(pb.BannerRotation_RecordClicks_FullMethodName),
+			rateLimitClient(stream.Context())); err != nil {
+			response.Rejected++
+			continue
+		}
+
+		if err := api.RecordClick(stream.Context(), recordClickRequest(req)); err != nil {
+			response.Rejected++
+			continue
+		}
+		response.Accepted++
+	}
+}
+
+func (s *Server) AddBanner(ctx context.Context, req *pb.AddBannerRequest) (*pb.AddBannerResponse, error) {
+	err := api.AddBanner(ctx, m.AddBannerRequest{
+		SlotID:   e.SlotID(req.GetSlotId()),
+		BannerID: e.BannerID(req.GetBannerId()),
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.AddBannerResponse{}, nil
+}
+
+func (s *Server) RemoveBanner(ctx context.Context, req *pb.RemoveBannerRequest) (*pb.RemoveBannerResponse, error) {
+	err := api.RemoveBanner(ctx, m.RemoveBannerRequest{
+		SlotID:   e.SlotID(req.GetSlotId()),
+		BannerID: e.BannerID(req.GetBannerId()),
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.RemoveBannerResponse{}, nil
+}
+
+func recordClickRequest(req *pb.RecordClickRequest) m.RecordClickRequest {
+	return m.RecordClickRequest{
+		SlotID:       e.SlotID(req.GetSlotId()),
+		BannerID:     e.BannerID(req.GetBannerId()),
+		UserGroupID:  e.UserGroupID(req.GetUserGroupId()),
+		ImpressionID: req.GetImpressionId(),
+	}
+}
+
+func creative(c *e.Creative) *pb.Creative {
+	if c == nil {
+		return nil
+	}
+	return &pb.Creative{
+		ImageUrl:  c.ImageURL,
+		AltText:   c.AltText,
+		TargetUrl: c.TargetURL,
+		Width:     int32(c.Width),
+		Height:    int32(c.Height),
+		Format:    string(c.Format),
+	}
+}
+
+func toStatus(err error) error {
+	var requestErr *api.RequestError
+	if !errors.As(err, &requestErr) {
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	code := codes.Internal
+	switch requestErr.Status {
+	case http.StatusBadRequest:
+		code = codes.InvalidArgument
+	case http.StatusUnauthorized:
+		code = codes.Unauthenticated
+	case http.StatusForbidden:
+		code = codes.PermissionDenied
+	case http.StatusNotFound, http.StatusGone:
+		code = codes.NotFound
+	case http.StatusConflict:
+		code = codes.FailedPrecondition
+	case http.StatusRequestEntityTooLarge, http.StatusTooManyRequests:
+		code = codes.ResourceExhausted
+	case http.StatusServiceUnavailable:
+		code = codes.Unavailable
+	}
+	return status.Error(code, requestErr.Message)
+}
+
+func requireReadyUnary(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	if !ready() {
+		return nil, status.Error(codes.Unavailable, "Service is starting")
+	}
+	return handler(ctx, req)
+}
+
+func requireReadyStream(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	if !ready() {
+		return status.Error(codes.Unavailable, "Service is starting")
+	}
+	return handler(srv, ss)
+}
+
+func authorizeUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	principal, err := authorize(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(auth.WithPrincipal(ctx, principal), req)
+}
+
+func authorizeStream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	principal, err := authorize(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &principalStream{ServerStream: ss, ctx: auth.WithPrincipal(ss.Context(), principal)})
+}
+
+type principalStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *principalStream) Context() context.Context {
+	return s.ctx
+}
+
+// authorize reads credentials from the "authorization" (Bearer) or
+// "x-api-key" metadata, the same way the HTTP API reads its headers.
+func authorize(ctx context.Context, fullMethod string) (*auth.Principal, error) {
+	role, ok := methodRoles[fullMethod]
+	if !ok {
+		return nil, status.Error(codes.PermissionDenied, "Unknown method")
+	}
+
+	var token string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			token = auth.TokenFromHeader(values[0])
+		}
+		if values := md.Get("x-api-key"); token == "" && len(values) > 0 {
+			token = strings.TrimSpace(values[0])
+		}
+	}
+
+	principal, err := api.Authorize(ctx, token, role)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return principal, nil
+}
+
+// limitIPUnary and limitIPStream limit calls per client IP before they are
+// authenticated.
+func limitIPUnary(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	if err := api.AllowIP(peerIP(ctx)); err != nil {
+		return nil, status.Error(codes.ResourceExhausted, err.Error())
+	}
+	return handler(ctx, req)
+}
+
+func limitIPStream(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	if err := api.AllowIP(peerIP(ss.Context())); err != nil {
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+	return handler(srv, ss)
+}
+
+func rateLimitUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	if err := api.Allow(rateLimitOperation(info.FullMethod), rateLimitClient(ctx)); err != nil {
+		return nil, status.Error(codes.ResourceExhausted, err.Error())
+	}
+	return handler(ctx, req)
+}
+
+// rateLimitOperation names gRPC methods "grpc.<Method>" in rate limit settings.
+func rateLimitOperation(fullMethod string) string {
+	return "grpc." + fullMethod[strings.LastIndex(fullMethod, "/")+1:]
+}
+
+func rateLimitClient(ctx context.Context) string {
+	if principal, ok := auth.PrincipalFromContext(ctx); ok && principal.Subject != "anonymous" {
+		return principal.Subject
+	}
+	return peerIP(ctx)
+}
+
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "unknown"
+	}
+
+	var realIP string
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get("x-real-ip"); len(values) > 0 {
+		realIP = values[0]
+	}
+	return api.ClientIP(p.Addr.String(), md.Get("x-forwarded-for"), realIP)
+}
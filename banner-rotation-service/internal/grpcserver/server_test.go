@@ -0,0 +1,202 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+	"testing"
+
+	"github.com/yuriiwanchev/banner-rotation-service/internal/api"
+	e "github.com/yuriiwanchev/banner-rotation-service/internal/entities"
+	"github.com/yuriiwanchev/banner-rotation-service/internal/repository/apikeyrepository"
+	"github.com/yuriiwanchev/banner-rotation-service/internal/repository/bannerrepository"
+	"github.com/yuriiwanchev/banner-rotation-service/internal/repository/slotbannersrepository"
+	"github.com/yuriiwanchev/banner-rotation-service/internal/repository/slotrepository"
+	"github.com/yuriiwanchev/banner-rotation-service/internal/repository/statisticrepository"
+	"github.com/yuriiwanchev/banner-rotation-service/internal/repository/usergrouprepository"
+	"github.com/yuriiwanchev/banner-rotation-service/pkg/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestToStatus(t *testing.T) {
+	tests := []struct {
+		err  error
+		code codes.Code
+	}{
+		{&api.RequestError{Status: http.StatusBadRequest, Message: "bad"}, codes.InvalidArgument},
+		{&api.RequestError{Status: http.StatusUnauthorized, Message: "no key"}, codes.Unauthenticated},
+		{&api.RequestError{Status: http.StatusForbidden, Message: "role"}, codes.PermissionDenied},
+		{&api.RequestError{Status: http.StatusNotFound, Message: "missing"}, codes.NotFound},
+		{&api.RequestError{Status: http.StatusConflict, Message: "no confirmation"}, codes.FailedPrecondition},
+		{&api.RequestError{Status: http.StatusGone, Message: "expired"}, codes.NotFound},
+		{&api.RequestError{Status: http.StatusRequestEntityTooLarge, Message: "too large"}, codes.ResourceExhausted},
+		{&api.RequestError{Status: http.StatusServiceUnavailable, Message: "starting"}, codes.Unavailable},
+		{&api.RequestError{Status: http.StatusInternalServerError, Message: "db"}, codes.Internal},
+		{errors.New("unexpected"), codes.Internal},
+	}
+
+	for _, tt := range tests {
+		if got := status.Code(toStatus(tt.err)); got != tt.code {
+			t.Errorf("toStatus(%v) = %v, expected %v", tt.err, got, tt.code)
+		}
+	}
+}
+
+func TestPeerIP(t *testing.T) {
+	api.InitTrustedProxies([]netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")})
+	t.Cleanup(func() { api.InitTrustedProxies(nil) })
+
+	proxied := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1)}})
+	direct := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(203, 0, 113, 5)}})
+	forwarded := metadata.Pairs("x-forwarded-for", "198.51.100.1")
+
+	tests := []struct {
+		ctx      context.Context
+		expected string
+	}{
+		{proxied, "ip:10.0.0.1"},
+		{metadata.NewIncomingContext(proxied, forwarded), "ip:198.51.100.1"},
+		{metadata.NewIncomingContext(proxied, metadata.Pairs("x-real-ip", "198.51.100.2")), "ip:198.51.100.2"},
+		{metadata.NewIncomingContext(direct, forwarded), "ip:203.0.113.5"},
+	}
+
+	for i, tt := range tests {
+		if ip := peerIP(tt.ctx); ip != tt.expected {
+			t.Errorf("case %d: peerIP = %q, expected %q", i, ip, tt.expected)
+		}
+	}
+}
+
+func TestRequireReadyUnary(t *testing.T) {
+	ready = func() bool { return false }
+	t.Cleanup(func() { ready = api.Ready })
+
+	called := false
+	handler := func(context.Context, interface{}) (interface{}, error) {
+		called = true
+		return &pb.SelectBannerResponse{}, nil
+	}
+
+	_, err := requireReadyUnary(context.Background(), &pb.SelectBannerRequest{}, nil, handler)
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected Unavailable before warm-up, got %v", err)
+	}
+	if called {
+		t.Errorf("Handler was called before warm-up")
+	}
+}
+
+// startTestServer serves the API backed by in-memory repositories, holding
+// slot 1 and user group 1, over an in-process connection.
+func startTestServer(t *testing.T) (pb.BannerRotationClient, *statisticrepository.MemStatisticRepository) {
+	t.Helper()
+
+	ctx := context.Background()
+	slots := slotrepository.NewMemSlotRepository()
+	slots.CreateSlot(ctx, &e.Slot{ID: 1})
+	userGroups := usergrouprepository.NewMemUserGroupRepository()
+	userGroups.CreateUserGroup(ctx, &e.UserGroup{ID: 1})
+	statistics := statisticrepository.NewMemStatisticRepository()
+
+	api.SetRepositories(api.Repositories{
+		Slots:       slots,
+		Banners:     bannerrepository.NewMemBannerRepository(),
+		SlotBanners: slotbannersrepository.NewMemSlotBannerRepository(),
+		Statistics:  statistics,
+		UserGroups:  userGroups,
+		APIKeys:     apikeyrepository.NewMemAPIKeyRepository(),
+	})
+	api.InitRotationAlgorithm()
+
+	listener := bufconn.Listen(1 << 20)
+	server := NewGRPCServer()
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return pb.NewBannerRotationClient(conn), statistics
+}
+
+func TestServer(t *testing.T) {
+	client, statistics := startTestServer(t)
+	ctx := context.Background()
+
+	if _, err := client.AddBanner(ctx, &pb.AddBannerRequest{SlotId: 1, BannerId: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	selection, err := client.SelectBanner(ctx, &pb.SelectBannerRequest{SlotId: 1, UserGroupId: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if selection.GetBannerId() != 1 {
+		t.Errorf("Expected banner 1, got %d", selection.GetBannerId())
+	}
+
+	if _, err := client.RecordClick(ctx, &pb.RecordClickRequest{SlotId: 1, BannerId: 1, UserGroupId: 1}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.RecordClick(ctx, &pb.RecordClickRequest{SlotId: 1, BannerId: 1})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument without a user group, got %v", err)
+	}
+
+	stream, err := client.RecordClicks(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, req := range []*pb.RecordClickRequest{
+		{SlotId: 1, BannerId: 1, UserGroupId: 1},
+		{SlotId: 1, BannerId: 1},
+		{SlotId: 1, BannerId: 1, UserGroupId: 1},
+	} {
+		if err := stream.Send(req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	summary, err := stream.CloseAndRecv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.GetAccepted() != 2 || summary.GetRejected() != 1 {
+		t.Errorf("Expected 2 accepted and 1 rejected clicks, got %v", summary)
+	}
+
+	stat, err := statistics.GetStatistics(ctx, 1, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.Views != 1 || stat.Clicks != 3 {
+		t.Errorf("Expected 1 view and 3 clicks, got %+v", stat)
+	}
+
+	_, err = client.RecordView(ctx, &pb.RecordViewRequest{ImpressionId: selection.GetImpressionId()})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Expected FailedPrecondition while views need no confirmation, got %v", err)
+	}
+
+	if _, err := client.RemoveBanner(ctx, &pb.RemoveBannerRequest{SlotId: 1, BannerId: 1}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.SelectBanner(ctx, &pb.SelectBannerRequest{SlotId: 1, UserGroupId: 1})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound once the banner is removed, got %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+// Package impression issues and verifies impression IDs: signed tokens that
+// carry what was shown to whom, so that any replica can attribute a later
+// click or view without shared state.
+package impression
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"time"
+
+	e "github.com/yuriiwanchev/banner-rotation-service/internal/entities"
+)
+
+// macSize is the length of the truncated HMAC-SHA256 appended to a token.
+const macSize = 16
+
+// flagControl marks the impressions of the holdout control group. Flags follow
+// the nonce and are left out when none is set, so IDs issued before they were
+// introduced still decode.
+const flagControl = 1
+
+var ErrInvalid = errors.New("invalid impression ID")
+
+// Impression is a banner of a slot shown to a user group.
+type Impression struct {
+	SlotID      e.SlotID
+	BannerID    e.BannerID
+	UserGroupID e.UserGroupID
+	ShownAt     time.Time
+	// Nonce makes the IDs of otherwise identical impressions differ.
+	Nonce uint64
+	// Control is set if the banner was picked at random for the holdout control group.
+	Control bool
+}
+
+// New returns an impression shown at now with a random nonce.
+func New(slotID e.SlotID, bannerID e.BannerID, groupID e.UserGroupID, now time.Time) Impression {
+	var nonce [8]byte
+	_, _ = rand.Read(nonce[:])
+	return Impression{
+		SlotID:      slotID,
+		BannerID:    bannerID,
+		UserGroupID: groupID,
+		ShownAt:     now.Truncate(time.Second),
+		Nonce:       binary.BigEndian.Uint64(nonce[:]),
+	}
+}
+
+// Signer encodes impressions into IDs and verifies them. Replicas must share
+// the key to accept each other's IDs.
+type Signer struct {
+	key []byte
+}
+
+func NewSigner(key []byte) *Signer {
+	return &Signer{key: key}
+}
+
+// Encode returns the URL-safe ID of the impression.
+func (s *Signer) Encode(imp Impression) string {
+	payload := binary.AppendUvarint(nil, uint64(imp.SlotID))
+	payload = binary.AppendUvarint(payload, uint64(imp.BannerID))
+	payload = binary.AppendUvarint(payload, uint64(imp.UserGroupID))
+	payload = binary.AppendVarint(payload, imp.ShownAt.Unix())
+	payload = binary.BigEndian.AppendUint64(payload, imp.Nonce)
+	if imp.Control {
+		payload = append(payload, flagControl)
+	}
+	return base64.RawURLEncoding.EncodeToString(append(payload, s.mac(payload)...))
+}
+
+// Decode verifies an ID and returns the impression it was issued for.
+func (s *Signer) Decode(id string) (Impression, error) {
+	token, err := base64.RawURLEncoding.DecodeString(id)
+	if err != nil || len(token) <= macSize {
+		return Impression{}, ErrInvalid
+	}
+	payload, mac := token[:len(token)-macSize], token[len(token)-macSize:]
+	if !hmac.Equal(mac, s.mac(payload)) {
+		return Impression{}, ErrInvalid
+	}
+
+	var fields [3]uint64
+	for i := range fields {
+		value, n := binary.Uvarint(payload)
+		if n <= 0 {
+			return Impression{}, ErrInvalid
+		}
+		fields[i], payload = value, payload[n:]
+	}
+	shownAt, n := binary.Varint(payload)
+	if n <= 0 || len(payload[n:]) < 8 {
+		return Impression{}, ErrInvalid
+	}
+	nonce, flags := payload[n:n+8], payload[n+8:]
+	if len(flags) > 1 || len(flags) == 1 && flags[0] != flagControl {
+		return Impression{}, ErrInvalid
+	}
+
+	return Impression{
+		SlotID:      e.SlotID(fields[0]),
+		BannerID:    e.BannerID(fields[1]),
+		UserGroupID: e.UserGroupID(fields[2]),
+		ShownAt:     time.Unix(shownAt, 0),
+		Nonce:       binary.BigEndian.Uint64(nonce),
+		Control:     len(flags) == 1,
+	}, nil
+}
+
+func (s *Signer) mac(payload []byte) []byte {
+	h := hmac.New(sha256.New, s.key)
+	h.Write(payload)
+	return h.Sum(nil)[:macSize]
+}
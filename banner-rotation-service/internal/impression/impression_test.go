@@ -0,0 +1,47 @@
+package impression
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	signer := NewSigner([]byte("secret"))
+	imp := New(3, 42, 2, time.Now())
+
+	id := signer.Encode(imp)
+	decoded, err := signer.Decode(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded != imp {
+		t.Errorf("Expected %+v, got %+v", imp, decoded)
+	}
+
+	if other := signer.Encode(New(3, 42, 2, imp.ShownAt)); other == id {
+		t.Error("Expected IDs of separate impressions to differ")
+	}
+
+	imp.Control = true
+	if decoded, err := signer.Decode(signer.Encode(imp)); err != nil || !decoded.Control {
+		t.Errorf("Expected a control impression, got %+v, %v", decoded, err)
+	}
+}
+
+func TestDecodeRejectsForgedIDs(t *testing.T) {
+	signer := NewSigner([]byte("secret"))
+	id := signer.Encode(New(3, 42, 2, time.Now()))
+
+	tampered := []byte(id)
+	tampered[0] ^= 1
+	for _, forged := range []string{
+		"",
+		"not base64!",
+		string(tampered),
+		NewSigner([]byte("other")).Encode(New(3, 42, 2, time.Now())),
+	} {
+		if _, err := signer.Decode(forged); err == nil {
+			t.Errorf("Expected %q to be rejected", forged)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	e "github.com/yuriiwanchev/banner-rotation-service/internal/entities"
+)
+
+type Consumer struct {
+	Reader *kafka.Reader
+}
+
+// NewKafkaConsumer reads the events of every partition of topic published
+// after it started. Offsets are never committed, so groupID only has to be
+// unique per instance: a restarted instance does not replay events that are
+// already reflected in the database.
+func NewKafkaConsumer(brokers []string, topic, groupID string) *Consumer {
+	return &Consumer{
+		Reader: kafka.NewReader(kafka.ReaderConfig{
+			Brokers:     brokers,
+			Topic:       topic,
+			GroupID:     groupID,
+			StartOffset: kafka.LastOffset,
+			MaxWait:     250 * time.Millisecond,
+		}),
+	}
+}
+
+// Next blocks until the next event arrives or ctx is done.
+func (c *Consumer) Next(ctx context.Context) (e.Event, error) {
+	var event e.Event
+
+	msg, err := c.Reader.FetchMessage(ctx)
+	if err != nil {
+		return event, err
+	}
+	if err := json.Unmarshal(msg.Value, &event); err != nil {
+		return event, fmt.Errorf("invalid event at offset %d: %w", msg.Offset, err)
+	}
+	return event, nil
+}
+
+func (c *Consumer) Close() error {
+	return c.Reader.Close()
+}
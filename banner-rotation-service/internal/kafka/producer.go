@@ -2,14 +2,19 @@ package kafka
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"log"
+	"strconv"
 	"time"
 
 	"github.com/segmentio/kafka-go"
+	e "github.com/yuriiwanchev/banner-rotation-service/internal/entities"
 )
 
 type Producer struct {
-	Writer *kafka.Writer
+	Writer  *kafka.Writer
+	brokers []string
 }
 
 func NewKafkaProducer(brokers []string, topic string) *Producer {
@@ -19,6 +24,7 @@ func NewKafkaProducer(brokers []string, topic string) *Producer {
 			Topic:    topic,
 			Balancer: &kafka.LeastBytes{},
 		},
+		brokers: brokers,
 	}
 }
 
@@ -36,6 +42,34 @@ func (p *Producer) PublishMessage(key, value []byte) error {
 	return nil
 }
 
+// PublishEvent writes event as JSON keyed by its slot ID, so that the events
+// of a slot stay ordered within one partition.
+func (p *Producer) PublishEvent(event e.Event) error {
+	eventBytes, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return p.PublishMessage([]byte(strconv.Itoa(int(event.SlotID))), eventBytes)
+}
+
+// Ping checks that at least one of the configured brokers accepts connections.
+func (p *Producer) Ping(ctx context.Context) error {
+	if len(p.brokers) == 0 {
+		return errors.New("no kafka brokers configured")
+	}
+
+	var err error
+	for _, broker := range p.brokers {
+		var conn *kafka.Conn
+		conn, err = kafka.DialContext(ctx, "tcp", broker)
+		if err != nil {
+			continue
+		}
+		return conn.Close()
+	}
+	return err
+}
+
 func (p *Producer) Close() error {
 	return p.Writer.Close()
 }
@@ -1,27 +1,102 @@
 package bandit
 
 import (
+	"cmp"
 	"fmt"
 	"log"
 	"math"
+	"math/rand/v2"
+	"slices"
 	"sync"
+	"time"
 
 	e "github.com/yuriiwanchev/banner-rotation-service/internal/entities"
 )
 
 type GroupStats struct {
-	Views  int
-	Clicks int
+	Views       int
+	Clicks      int
+	Conversions int
+	// Revenue is the total value of the conversions and RevenueSquares the
+	// sum of their squares.
+	Revenue        float64
+	RevenueSquares float64
+	// Pending counts selections awaiting confirmation of their view. They are
+	// taken as views while choosing banners, so that concurrent selections do
+	// not all pick the same banner, but are not persisted.
+	Pending int
 }
 
+// Slot holds the banners of a slot and their statistics per user group.
+// Operations on different slots do not contend: each slot has its own lock.
 type Slot struct {
 	Banners   map[e.BannerID]e.Banner
 	GroupData map[e.UserGroupID]map[e.BannerID]*GroupStats
+	// Schedules restrict when banners may be selected. Banners without a
+	// schedule are always selectable.
+	Schedules map[e.BannerID]Schedule
+	// Delivered counts the views of each banner, across user groups, on
+	// DeliveryDay. Daily impression goals are paced against it.
+	Delivered   map[e.BannerID]int
+	DeliveryDay time.Time
+	// Goal is the reward maximized per impression; empty means GoalCTR.
+	Goal e.OptimizationGoal
+	// Strategy picks the banners; empty means StrategyUCB1.
+	Strategy e.RotationStrategy
+	// HoldoutPercent of the selections pick a banner uniformly at random, as
+	// a control group to measure the bandit against.
+	HoldoutPercent int
+	// Exploration scales the exploration bonus of the strategy; 0 means 1.
+	Exploration float64
+
+	mu sync.Mutex
+	// groups caches what a selection needs per user group. It is built from
+	// Banners and GroupData on first use and dropped when the banners change.
+	groups map[e.UserGroupID]*groupArms
+}
+
+// groupArms are the banners of a slot with their statistics for one user
+// group, and the running total of their views, pending ones included.
+type groupArms struct {
+	arms       []arm
+	totalViews int
+}
+
+type arm struct {
+	bannerID e.BannerID
+	stats    *GroupStats
+	schedule Schedule
+}
+
+// Schedule is the flight of a banner in a slot and its daily impression
+// goals. Zero times are unbounded and zero goals are not set.
+type Schedule struct {
+	StartsAt            time.Time
+	EndsAt              time.Time
+	Paused              bool
+	MinDailyImpressions int
+	MaxDailyImpressions int
+}
+
+// ActiveAt reports whether the banner may be selected at now.
+func (s Schedule) ActiveAt(now time.Time) bool {
+	return !s.Paused &&
+		(s.StartsAt.IsZero() || !now.Before(s.StartsAt)) &&
+		(s.EndsAt.IsZero() || now.Before(s.EndsAt))
+}
+
+// Day returns the start of the UTC day containing t, which daily impression
+// goals are counted per.
+func Day(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
 }
 
+// MultiArmedBandit only takes its own lock exclusively to add or replace
+// slots; everything else reads the slot map under a shared lock and then
+// locks the one slot it works on.
 type MultiArmedBandit struct {
 	slots map[e.SlotID]*Slot
-	mu    sync.Mutex
+	mu    sync.RWMutex
 }
 
 func NewMultiArmedBandit(slots map[e.SlotID]*Slot) *MultiArmedBandit {
@@ -30,11 +105,34 @@ func NewMultiArmedBandit(slots map[e.SlotID]*Slot) *MultiArmedBandit {
 	}
 }
 
-func (mab *MultiArmedBandit) AddBanner(slotID e.SlotID, bannerID e.BannerID) {
+// Replace swaps in a freshly loaded set of slots. Only the swap happens under
+// the lock, so selections are not held up while the new state is built.
+func (mab *MultiArmedBandit) Replace(slots map[e.SlotID]*Slot) {
 	mab.mu.Lock()
 	defer mab.mu.Unlock()
 
+	mab.slots = slots
+}
+
+func (mab *MultiArmedBandit) slot(slotID e.SlotID) (*Slot, bool) {
+	mab.mu.RLock()
+	defer mab.mu.RUnlock()
+
 	slot, exists := mab.slots[slotID]
+	return slot, exists
+}
+
+// slotOrNew returns the slot, adding an empty one if it does not exist.
+func (mab *MultiArmedBandit) slotOrNew(slotID e.SlotID) *Slot {
+	slot, exists := mab.slot(slotID)
+	if exists {
+		return slot
+	}
+
+	mab.mu.Lock()
+	defer mab.mu.Unlock()
+
+	slot, exists = mab.slots[slotID]
 	if !exists {
 		slot = &Slot{
 			Banners:   make(map[e.BannerID]e.Banner),
@@ -42,105 +140,447 @@ func (mab *MultiArmedBandit) AddBanner(slotID e.SlotID, bannerID e.BannerID) {
 		}
 		mab.slots[slotID] = slot
 	}
+	return slot
+}
+
+func (mab *MultiArmedBandit) AddBanner(slotID e.SlotID, bannerID e.BannerID) {
+	slot := mab.slotOrNew(slotID)
+
+	slot.mu.Lock()
+	defer slot.mu.Unlock()
 
 	slot.Banners[bannerID] = e.Banner{ID: bannerID}
+	delete(slot.Schedules, bannerID)
+	slot.groups = nil
 }
 
-func (mab *MultiArmedBandit) RemoveBanner(slotID e.SlotID, bannerID e.BannerID) error {
-	mab.mu.Lock()
-	defer mab.mu.Unlock()
+// SetSchedule replaces the schedule of a banner already in the slot. Its
+// statistics are kept, so a paused banner resumes where it left off.
+func (mab *MultiArmedBandit) SetSchedule(slotID e.SlotID, bannerID e.BannerID, schedule Schedule) error {
+	slot, exists := mab.slot(slotID)
+	if !exists {
+		return fmt.Errorf("slot %d does not exist", slotID)
+	}
 
-	slot, exists := mab.slots[slotID]
+	slot.mu.Lock()
+	defer slot.mu.Unlock()
+
+	if _, exists := slot.Banners[bannerID]; !exists {
+		return fmt.Errorf("banner %d is not in slot %d", bannerID, slotID)
+	}
+	if slot.Schedules == nil {
+		slot.Schedules = make(map[e.BannerID]Schedule)
+	}
+	slot.Schedules[bannerID] = schedule
+	slot.groups = nil
+
+	return nil
+}
+
+// SetGoal changes the reward maximized in the slot. Statistics are kept for
+// every goal, so the bandit carries on from what it has learned.
+func (mab *MultiArmedBandit) SetGoal(slotID e.SlotID, goal e.OptimizationGoal) {
+	slot := mab.slotOrNew(slotID)
+
+	slot.mu.Lock()
+	defer slot.mu.Unlock()
+
+	slot.Goal = goal
+}
+
+// SetStrategy changes the algorithm banners of the slot are picked with.
+func (mab *MultiArmedBandit) SetStrategy(slotID e.SlotID, strategy e.RotationStrategy) {
+	slot := mab.slotOrNew(slotID)
+
+	slot.mu.Lock()
+	defer slot.mu.Unlock()
+
+	slot.Strategy = strategy
+}
+
+// SetHoldout changes the percentage of selections in the slot served at random.
+func (mab *MultiArmedBandit) SetHoldout(slotID e.SlotID, percent int) {
+	slot := mab.slotOrNew(slotID)
+
+	slot.mu.Lock()
+	defer slot.mu.Unlock()
+
+	slot.HoldoutPercent = percent
+}
+
+func (mab *MultiArmedBandit) RemoveBanner(slotID e.SlotID, bannerID e.BannerID) error {
+	slot, exists := mab.slot(slotID)
 	if !exists {
 		return fmt.Errorf("slot %d does not exist", slotID)
 	}
 
+	slot.mu.Lock()
+	defer slot.mu.Unlock()
+
 	delete(slot.Banners, bannerID)
 	for _, groupStats := range slot.GroupData {
 		delete(groupStats, bannerID)
 	}
+	delete(slot.Schedules, bannerID)
+	slot.groups = nil
 
 	return nil
 }
 
 func (mab *MultiArmedBandit) RecordClick(slotID e.SlotID, bannerID e.BannerID, groupID e.UserGroupID) error {
-	mab.mu.Lock()
-	defer mab.mu.Unlock()
+	slot, exists := mab.slot(slotID)
+	if !exists {
+		return fmt.Errorf("slot %d does not exist", slotID)
+	}
 
-	slot, exists := mab.slots[slotID]
+	slot.mu.Lock()
+	defer slot.mu.Unlock()
+
+	slot.statsFor(bannerID, groupID).Clicks++
+
+	return nil
+}
+
+// RecordConversion counts a conversion of a banner and adds its value to the revenue.
+func (mab *MultiArmedBandit) RecordConversion(slotID e.SlotID, bannerID e.BannerID, groupID e.UserGroupID,
+	value float64,
+) error {
+	slot, exists := mab.slot(slotID)
 	if !exists {
 		return fmt.Errorf("slot %d does not exist", slotID)
 	}
 
-	groupStats, exists := slot.GroupData[groupID]
+	slot.mu.Lock()
+	defer slot.mu.Unlock()
+
+	stats := slot.statsFor(bannerID, groupID)
+	stats.Conversions++
+	stats.Revenue += value
+	stats.RevenueSquares += value * value
+
+	return nil
+}
+
+// RecordView counts a view of a banner selected elsewhere, e.g. by another replica.
+func (mab *MultiArmedBandit) RecordView(slotID e.SlotID, bannerID e.BannerID, groupID e.UserGroupID) error {
+	slot, exists := mab.slot(slotID)
 	if !exists {
-		groupStats = make(map[e.BannerID]*GroupStats)
-		slot.GroupData[groupID] = groupStats
+		return fmt.Errorf("slot %d does not exist", slotID)
 	}
 
-	stats, exists := groupStats[bannerID]
+	slot.mu.Lock()
+	defer slot.mu.Unlock()
+
+	slot.recordView(bannerID, groupID)
+
+	return nil
+}
+
+// ConfirmView turns a pending selection into a view. Without a pending
+// selection, e.g. when it was made by another replica, a view is counted as by
+// RecordView.
+func (mab *MultiArmedBandit) ConfirmView(slotID e.SlotID, bannerID e.BannerID, groupID e.UserGroupID) error {
+	slot, exists := mab.slot(slotID)
 	if !exists {
-		stats = &GroupStats{}
-		groupStats[bannerID] = stats
+		return fmt.Errorf("slot %d does not exist", slotID)
 	}
 
-	stats.Clicks++
+	slot.mu.Lock()
+	defer slot.mu.Unlock()
+
+	stats := slot.statsFor(bannerID, groupID)
+	if stats.Pending == 0 {
+		slot.recordView(bannerID, groupID)
+		return nil
+	}
+	stats.Pending--
+	stats.Views++
 
 	return nil
 }
 
-func (mab *MultiArmedBandit) SelectBanner(slotID e.SlotID, groupID e.UserGroupID) e.BannerID {
-	mab.mu.Lock()
-	defer mab.mu.Unlock()
+// ExpireView drops a pending selection that was never confirmed.
+func (mab *MultiArmedBandit) ExpireView(slotID e.SlotID, bannerID e.BannerID, groupID e.UserGroupID) error {
+	slot, exists := mab.slot(slotID)
+	if !exists {
+		return fmt.Errorf("slot %d does not exist", slotID)
+	}
 
-	slot, exists := mab.slots[slotID]
+	slot.mu.Lock()
+	defer slot.mu.Unlock()
+
+	stats := slot.statsFor(bannerID, groupID)
+	if stats.Pending == 0 {
+		return nil
+	}
+	stats.Pending--
+	if group, cached := slot.groups[groupID]; cached {
+		if _, isArm := slot.Banners[bannerID]; isArm {
+			group.totalViews--
+		}
+	}
+	if delivered := slot.deliveredOn(time.Now()); delivered[bannerID] > 0 {
+		delivered[bannerID]--
+	}
+
+	return nil
+}
+
+// GetStats returns a copy of the statistics of a banner for a user group.
+func (mab *MultiArmedBandit) GetStats(slotID e.SlotID, bannerID e.BannerID, groupID e.UserGroupID) (GroupStats, bool) {
+	slot, exists := mab.slot(slotID)
 	if !exists {
-		log.Printf("SelectBanner: slot %d does not exist", slotID)
-		return 0
+		return GroupStats{}, false
 	}
 
+	slot.mu.Lock()
+	defer slot.mu.Unlock()
+
+	stats, exists := slot.GroupData[groupID][bannerID]
+	if !exists {
+		return GroupStats{}, false
+	}
+	return *stats, true
+}
+
+// statsFor returns the statistics entry of a banner, creating it if needed.
+// The caller must hold slot.mu.
+func (slot *Slot) statsFor(bannerID e.BannerID, groupID e.UserGroupID) *GroupStats {
 	groupStats, exists := slot.GroupData[groupID]
 	if !exists {
 		groupStats = make(map[e.BannerID]*GroupStats)
 		slot.GroupData[groupID] = groupStats
 	}
 
-	var selectedBanner e.BannerID
-	maxUCB := -1.0
+	stats, exists := groupStats[bannerID]
+	if !exists {
+		stats = &GroupStats{}
+		groupStats[bannerID] = stats
+	}
+
+	return stats
+}
+
+// recordView counts a view made outside this bandit. The caller must hold slot.mu.
+func (slot *Slot) recordView(bannerID e.BannerID, groupID e.UserGroupID) {
+	slot.statsFor(bannerID, groupID).Views++
+	if _, isArm := slot.Banners[bannerID]; isArm {
+		if group, cached := slot.groups[groupID]; cached {
+			group.totalViews++
+		}
+		slot.deliveredOn(time.Now())[bannerID]++
+	}
+}
+
+// deliveredOn returns the daily views of the banners, starting a new count
+// when the day of now differs from DeliveryDay. The caller must hold slot.mu.
+func (slot *Slot) deliveredOn(now time.Time) map[e.BannerID]int {
+	if day := Day(now); slot.Delivered == nil || !day.Equal(slot.DeliveryDay) {
+		slot.Delivered = make(map[e.BannerID]int)
+		slot.DeliveryDay = day
+	}
+	return slot.Delivered
+}
+
+// groupFor returns the arms of a user group, building them if needed.
+// The caller must hold slot.mu.
+func (slot *Slot) groupFor(groupID e.UserGroupID) *groupArms {
+	if group, cached := slot.groups[groupID]; cached {
+		return group
+	}
 
+	group := &groupArms{arms: make([]arm, 0, len(slot.Banners))}
 	for bannerID := range slot.Banners {
-		stats, exists := groupStats[bannerID]
-		if !exists {
-			stats = &GroupStats{}
-			groupStats[bannerID] = stats
+		stats := slot.statsFor(bannerID, groupID)
+		group.arms = append(group.arms, arm{bannerID: bannerID, stats: stats, schedule: slot.Schedules[bannerID]})
+		group.totalViews += stats.Views + stats.Pending
+	}
+	// Ties, e.g. between banners not shown yet, go to the lowest banner ID
+	// rather than to the map order, so that selections are reproducible.
+	slices.SortFunc(group.arms, func(a, b arm) int { return cmp.Compare(a.bannerID, b.bannerID) })
+
+	if slot.groups == nil {
+		slot.groups = make(map[e.UserGroupID]*groupArms)
+	}
+	slot.groups[groupID] = group
+	return group
+}
+
+// Selection is a banner picked for a user group.
+type Selection struct {
+	BannerID e.BannerID
+	// Control is set if the banner was picked at random for the holdout
+	// control group rather than by the bandit.
+	Control bool
+}
+
+// SelectBanner picks a banner of the slot for the user group and counts its
+// view. Excluded banners, e.g. those the user has been shown too often, are
+// not candidates.
+func (mab *MultiArmedBandit) SelectBanner(slotID e.SlotID, groupID e.UserGroupID, exclude ...e.BannerID) e.BannerID {
+	return mab.Select(slotID, groupID, false, exclude...).BannerID
+}
+
+// SelectBannerPending picks a banner like SelectBanner, but leaves its view
+// pending until ConfirmView or ExpireView.
+func (mab *MultiArmedBandit) SelectBannerPending(slotID e.SlotID, groupID e.UserGroupID,
+	exclude ...e.BannerID,
+) e.BannerID {
+	return mab.Select(slotID, groupID, true, exclude...).BannerID
+}
+
+// Select picks a banner like SelectBanner, or like SelectBannerPending if
+// pending is set, and also tells whether it went to the control group. The
+// selection has no banner if none is available.
+func (mab *MultiArmedBandit) Select(slotID e.SlotID, groupID e.UserGroupID, pending bool,
+	exclude ...e.BannerID,
+) Selection {
+	slot, exists := mab.slot(slotID)
+	if !exists {
+		log.Printf("SelectBanner: slot %d does not exist", slotID)
+		return Selection{}
+	}
+
+	slot.mu.Lock()
+	defer slot.mu.Unlock()
+
+	group := slot.groupFor(groupID)
+
+	var selected, behind *arm
+	var eligible []*arm
+	control := slot.HoldoutPercent > 0 && rand.IntN(100) < slot.HoldoutPercent
+	maxIndex := math.Inf(-1)
+	maxDeficit := 0.0
+	now := time.Now()
+	delivered := slot.deliveredOn(now)
+	dayElapsed := float64(now.Sub(slot.DeliveryDay)) / float64(24*time.Hour)
+	index := slot.indexFor(group.arms, math.Log(float64(group.totalViews)))
+
+	for i := range group.arms {
+		candidate := &group.arms[i]
+		schedule := candidate.schedule
+		if !schedule.ActiveAt(now) || slices.Contains(exclude, candidate.bannerID) {
+			continue
+		}
+		if schedule.MaxDailyImpressions > 0 && delivered[candidate.bannerID] >= schedule.MaxDailyImpressions {
+			continue
+		}
+		if control {
+			eligible = append(eligible, candidate)
+			continue
 		}
 
-		ucb := calculateUCB(stats.Clicks, stats.Views, totalViews(groupStats))
+		// A banner with a minimum is paced evenly over the day: while it is
+		// behind that pace it is shown regardless of its performance, the one
+		// furthest behind first.
+		if schedule.MinDailyImpressions > 0 {
+			deficit := float64(schedule.MinDailyImpressions)*dayElapsed - float64(delivered[candidate.bannerID])
+			if deficit > maxDeficit {
+				maxDeficit = deficit
+				behind = candidate
+			}
+		}
 
-		if ucb > maxUCB {
-			maxUCB = ucb
-			selectedBanner = bannerID
+		if value := index(candidate.stats); value > maxIndex {
+			maxIndex = value
+			selected = candidate
 		}
 	}
 
-	if selectedBanner != 0 {
-		groupStats[selectedBanner].Views++
+	switch {
+	case control && len(eligible) > 0:
+		selected = eligible[rand.IntN(len(eligible))]
+	case behind != nil:
+		selected = behind
+	}
+	if selected == nil {
+		return Selection{}
 	}
 
-	return selectedBanner
+	if pending {
+		selected.stats.Pending++
+	} else {
+		selected.stats.Views++
+	}
+	group.totalViews++
+	delivered[selected.bannerID]++
+
+	return Selection{BannerID: selected.bannerID, Control: control}
+}
+
+// indexFor returns the index of the strategy of the slot for the arms of a
+// group; the arm with the highest index is selected. The caller must hold
+// slot.mu.
+func (slot *Slot) indexFor(arms []arm, logTotalViews float64) func(*GroupStats) float64 {
+	goal := slot.Goal
+	scale := rewardScale(goal, arms)
+	exploration := slot.Exploration
+	if exploration == 0 {
+		exploration = 1
+	}
+
+	if slot.Strategy == e.StrategyUCBV {
+		return func(stats *GroupStats) float64 {
+			sum, squares := stats.rewards(goal)
+			n := stats.Views + stats.Pending
+			return calculateUCBV(sum/scale, squares/(scale*scale), n, logTotalViews, exploration)
+		}
+	}
+	return func(stats *GroupStats) float64 {
+		sum, _ := stats.rewards(goal)
+		return calculateUCB(sum/scale, stats.Views+stats.Pending, logTotalViews, exploration)
+	}
+}
+
+// rewards returns the sum of the rewards of the impressions under the goal
+// and the sum of their squares. Clicks and conversions are rewards of 1.
+func (stats *GroupStats) rewards(goal e.OptimizationGoal) (sum, squares float64) {
+	switch goal {
+	case e.GoalConversions:
+		return float64(stats.Conversions), float64(stats.Conversions)
+	case e.GoalRevenue:
+		return stats.Revenue, stats.RevenueSquares
+	default:
+		return float64(stats.Clicks), float64(stats.Clicks)
+	}
+}
+
+// rewardScale bounds the reward of an impression, so that scaled rewards are
+// about 0 to 1 and the exploration terms keep their weight. For revenue it is
+// the highest root mean square conversion value among the arms, as single
+// values are not kept.
+func rewardScale(goal e.OptimizationGoal, arms []arm) float64 {
+	if goal != e.GoalRevenue {
+		return 1
+	}
+	scale := 0.0
+	for _, candidate := range arms {
+		if candidate.stats.Conversions > 0 {
+			scale = max(scale, math.Sqrt(candidate.stats.RevenueSquares/float64(candidate.stats.Conversions)))
+		}
+	}
+	if scale == 0 {
+		return 1
+	}
+	return scale
 }
 
-func calculateUCB(clicks, views, totalViews int) float64 {
+func calculateUCB(reward float64, views int, logTotalViews, exploration float64) float64 {
 	if views == 0 {
 		return 1e6
 	}
-	return float64(clicks)/float64(views) + 2.0*math.Sqrt(math.Log(float64(totalViews))/float64(views))
+	return reward/float64(views) + exploration*2.0*math.Sqrt(logTotalViews/float64(views))
 }
 
-func totalViews(groupStats map[e.BannerID]*GroupStats) int {
-	total := 0
-	for _, stats := range groupStats {
-		total += stats.Views
+// calculateUCBV is the UCB-V index of Audibert, Munos and Szepesvári for
+// rewards in [0, 1]: an arm whose rewards vary little is explored less than
+// UCB1 would.
+func calculateUCBV(reward, squares float64, views int, logTotalViews, exploration float64) float64 {
+	if views == 0 {
+		return 1e6
 	}
-	return total
+	n := float64(views)
+	mean := reward / n
+	variance := max(squares/n-mean*mean, 0)
+	return mean + exploration*(math.Sqrt(2*variance*logTotalViews/n)+3*logTotalViews/n)
 }
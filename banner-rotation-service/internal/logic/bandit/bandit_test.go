@@ -1,8 +1,12 @@
 package bandit
 
 import (
+	"fmt"
+	"math"
+	"math/rand/v2"
 	"sync"
 	"testing"
+	"time"
 
 	e "github.com/yuriiwanchev/banner-rotation-service/internal/entities"
 )
@@ -362,3 +366,401 @@ func isSignificantlyBigger(num1, num2 int, threshold float64) bool {
 
 	return relativeDifference >= threshold
 }
+
+func TestConcurrentOperationsAcrossSlots(t *testing.T) {
+	mab := NewMultiArmedBandit(make(map[e.SlotID]*Slot))
+	groupID := e.UserGroupID(1)
+
+	var wg sync.WaitGroup
+	for s := 1; s <= 20; s++ {
+		wg.Add(1)
+		go func(slotID e.SlotID) {
+			defer wg.Done()
+			for b := 1; b <= 5; b++ {
+				mab.AddBanner(slotID, e.BannerID(b))
+			}
+			for i := 0; i < 100; i++ {
+				bannerID := mab.SelectBanner(slotID, groupID)
+				if err := mab.RecordClick(slotID, bannerID, groupID); err != nil {
+					t.Errorf("Error recording click in slot %d: %v", slotID, err)
+				}
+			}
+			if err := mab.RemoveBanner(slotID, e.BannerID(5)); err != nil {
+				t.Errorf("Error removing banner from slot %d: %v", slotID, err)
+			}
+		}(e.SlotID(s))
+	}
+	wg.Wait()
+
+	for slotID, slot := range mab.slots {
+		views := 0
+		for _, stats := range slot.GroupData[groupID] {
+			views += stats.Views
+		}
+		if len(slot.Banners) != 4 {
+			t.Errorf("Expected 4 banners in slot %d, got %d", slotID, len(slot.Banners))
+		}
+		// Views of the removed banner are dropped with it.
+		if views > 100 {
+			t.Errorf("Expected at most 100 views in slot %d, got %d", slotID, views)
+		}
+	}
+}
+
+// newBenchmarkBandit creates slots with the given number of banners each, all
+// of which have been shown a few times.
+func newBenchmarkBandit(slots, banners int) *MultiArmedBandit {
+	mab := NewMultiArmedBandit(make(map[e.SlotID]*Slot))
+	for s := 1; s <= slots; s++ {
+		for b := 1; b <= banners; b++ {
+			mab.AddBanner(e.SlotID(s), e.BannerID(b))
+		}
+		for i := 0; i < 2*banners; i++ {
+			mab.SelectBanner(e.SlotID(s), 1)
+		}
+	}
+	return mab
+}
+
+func BenchmarkSelectBanner(b *testing.B) {
+	mab := newBenchmarkBandit(1, 10)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		mab.SelectBanner(1, 1)
+	}
+}
+
+func BenchmarkSelectBannerParallelManySlots(b *testing.B) {
+	const slots = 1000
+	mab := newBenchmarkBandit(slots, 10)
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		slotID := e.SlotID(rand.IntN(slots) + 1)
+		for pb.Next() {
+			mab.SelectBanner(slotID, 1)
+			slotID = slotID%slots + 1
+		}
+	})
+}
+
+func BenchmarkSelectBannerParallelOneSlot(b *testing.B) {
+	mab := newBenchmarkBandit(1, 10)
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			mab.SelectBanner(1, 1)
+		}
+	})
+}
+
+func BenchmarkMixedParallelManySlots(b *testing.B) {
+	const slots = 1000
+	mab := newBenchmarkBandit(slots, 10)
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		slotID := e.SlotID(rand.IntN(slots) + 1)
+		for i := 0; pb.Next(); i++ {
+			bannerID := mab.SelectBanner(slotID, 1)
+			if i%10 == 0 {
+				mab.RecordClick(slotID, bannerID, 1)
+			}
+			slotID = slotID%slots + 1
+		}
+	})
+}
+
+func BenchmarkSelectBannerManyBanners(b *testing.B) {
+	for _, banners := range []int{10, 100, 500} {
+		b.Run(fmt.Sprintf("banners=%d", banners), func(b *testing.B) {
+			mab := newBenchmarkBandit(1, banners)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				mab.SelectBanner(1, 1)
+			}
+		})
+	}
+}
+
+func TestRunningTotalViews(t *testing.T) {
+	mab := NewMultiArmedBandit(make(map[e.SlotID]*Slot))
+	slotID := e.SlotID(1)
+	groupID := e.UserGroupID(1)
+
+	for i := 1; i <= 5; i++ {
+		mab.AddBanner(slotID, e.BannerID(i))
+	}
+	for i := 0; i < 50; i++ {
+		mab.SelectBanner(slotID, groupID)
+	}
+	mab.RecordView(slotID, 2, groupID)
+	mab.RecordView(slotID, 42, groupID) // not a banner of the slot
+	mab.RemoveBanner(slotID, 3)
+	mab.AddBanner(slotID, 6)
+	for i := 0; i < 50; i++ {
+		mab.SelectBanner(slotID, groupID)
+	}
+	mab.RecordView(slotID, 6, groupID)
+
+	slot := mab.slots[slotID]
+	expected := 0
+	for bannerID := range slot.Banners {
+		expected += slot.GroupData[groupID][bannerID].Views
+	}
+	if total := slot.groups[groupID].totalViews; total != expected {
+		t.Errorf("Expected running total of %d views, got %d", expected, total)
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	mab := NewMultiArmedBandit(make(map[e.SlotID]*Slot))
+	slotID := e.SlotID(1)
+	groupID := e.UserGroupID(1)
+	now := time.Now()
+
+	mab.AddBanner(slotID, 1)
+	mab.AddBanner(slotID, 2)
+	mab.AddBanner(slotID, 3)
+
+	mab.SetSchedule(slotID, 1, Schedule{Paused: true})
+	mab.SetSchedule(slotID, 2, Schedule{StartsAt: now.Add(time.Hour)})
+	mab.SetSchedule(slotID, 3, Schedule{StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour)})
+
+	for i := 0; i < 10; i++ {
+		if selected := mab.SelectBanner(slotID, groupID); selected != 3 {
+			t.Fatalf("Expected only the banner in flight to be selected, got %d", selected)
+		}
+	}
+
+	mab.SetSchedule(slotID, 3, Schedule{EndsAt: now.Add(-time.Minute)})
+	if selected := mab.SelectBanner(slotID, groupID); selected != 0 {
+		t.Errorf("Expected no banner after every flight ended, got %d", selected)
+	}
+
+	mab.SetSchedule(slotID, 1, Schedule{})
+	if selected := mab.SelectBanner(slotID, groupID); selected != 1 {
+		t.Errorf("Expected the resumed banner to be selected, got %d", selected)
+	}
+
+	if err := mab.SetSchedule(slotID, 4, Schedule{}); err == nil {
+		t.Error("Expected an error scheduling a banner that is not in the slot")
+	}
+}
+
+func TestDailyImpressionGoals(t *testing.T) {
+	mab := NewMultiArmedBandit(make(map[e.SlotID]*Slot))
+	slotID := e.SlotID(1)
+	groupID := e.UserGroupID(1)
+
+	mab.AddBanner(slotID, 1)
+	mab.AddBanner(slotID, 2)
+	mab.AddBanner(slotID, 3)
+
+	// Banner 1 performs best, so without goals it would take most views.
+	mab.RecordClick(slotID, 1, groupID)
+	mab.SetSchedule(slotID, 1, Schedule{MaxDailyImpressions: 5})
+	mab.SetSchedule(slotID, 2, Schedule{MinDailyImpressions: 1_000_000})
+	mab.SetSchedule(slotID, 3, Schedule{Paused: true})
+
+	for i := 0; i < 10; i++ {
+		if selected := mab.SelectBanner(slotID, groupID); selected != 2 {
+			t.Fatalf("Expected the banner behind its minimum to be forced, got %d", selected)
+		}
+	}
+
+	mab.SetSchedule(slotID, 2, Schedule{Paused: true})
+	for i := 0; i < 5; i++ {
+		if selected := mab.SelectBanner(slotID, groupID); selected != 1 {
+			t.Fatalf("Expected banner 1 below its cap to be selected, got %d", selected)
+		}
+	}
+	if selected := mab.SelectBanner(slotID, groupID); selected != 0 {
+		t.Errorf("Expected a capped banner to be excluded, got %d", selected)
+	}
+
+	// Views replicated from other instances count towards the cap as well.
+	mab.SetSchedule(slotID, 1, Schedule{MaxDailyImpressions: 7})
+	mab.RecordView(slotID, 1, groupID)
+	mab.RecordView(slotID, 1, groupID)
+	if selected := mab.SelectBanner(slotID, groupID); selected != 0 {
+		t.Errorf("Expected replicated views to count towards the cap, got %d", selected)
+	}
+
+	// The count starts over on the next day.
+	slot, _ := mab.slot(slotID)
+	slot.DeliveryDay = slot.DeliveryDay.Add(-24 * time.Hour)
+	if selected := mab.SelectBanner(slotID, groupID); selected != 1 {
+		t.Errorf("Expected the cap to reset on a new day, got %d", selected)
+	}
+}
+
+func TestSelectBannerExclude(t *testing.T) {
+	mab := NewMultiArmedBandit(make(map[e.SlotID]*Slot))
+	slotID := e.SlotID(1)
+	groupID := e.UserGroupID(1)
+
+	mab.AddBanner(slotID, 1)
+	mab.AddBanner(slotID, 2)
+	mab.RecordClick(slotID, 1, groupID)
+
+	for i := 0; i < 10; i++ {
+		if selected := mab.SelectBanner(slotID, groupID, 1); selected != 2 {
+			t.Fatalf("Expected the excluded banner not to be selected, got %d", selected)
+		}
+	}
+	if selected := mab.SelectBanner(slotID, groupID, 1, 2); selected != 0 {
+		t.Errorf("Expected no banner when all are excluded, got %d", selected)
+	}
+}
+
+func TestPendingViews(t *testing.T) {
+	mab := NewMultiArmedBandit(make(map[e.SlotID]*Slot))
+	slotID := e.SlotID(1)
+	groupID := e.UserGroupID(1)
+
+	mab.AddBanner(slotID, 1)
+	mab.AddBanner(slotID, 2)
+
+	// Pending selections count while choosing, so two in a row explore both banners.
+	first := mab.SelectBannerPending(slotID, groupID)
+	second := mab.SelectBannerPending(slotID, groupID)
+	if first == second {
+		t.Errorf("Expected pending selections to spread over banners, got %d twice", first)
+	}
+
+	mab.ConfirmView(slotID, first, groupID)
+	if stats, _ := mab.GetStats(slotID, first, groupID); stats.Views != 1 || stats.Pending != 0 {
+		t.Errorf("Expected the confirmed selection to become a view, got %+v", stats)
+	}
+
+	mab.ExpireView(slotID, second, groupID)
+	if stats, _ := mab.GetStats(slotID, second, groupID); stats.Views != 0 || stats.Pending != 0 {
+		t.Errorf("Expected the expired selection to be dropped, got %+v", stats)
+	}
+
+	// A confirmation without a pending selection, e.g. from another replica, still counts.
+	mab.ConfirmView(slotID, second, groupID)
+	if stats, _ := mab.GetStats(slotID, second, groupID); stats.Views != 1 {
+		t.Errorf("Expected a view to be counted, got %+v", stats)
+	}
+
+	slot, _ := mab.slot(slotID)
+	if total := slot.groups[groupID].totalViews; total != 2 {
+		t.Errorf("Expected a running total of 2 views, got %d", total)
+	}
+}
+
+func TestOptimizationGoal(t *testing.T) {
+	mab := NewMultiArmedBandit(make(map[e.SlotID]*Slot))
+	slotID := e.SlotID(1)
+	groupID := e.UserGroupID(1)
+
+	// Banner 1 is clicked most, banner 2 converts most and banner 3 brings in the most revenue.
+	for bannerID, stats := range map[e.BannerID]GroupStats{
+		1: {Views: 1000, Clicks: 100, Conversions: 1, Revenue: 1, RevenueSquares: 1},
+		2: {Views: 1000, Clicks: 10, Conversions: 20, Revenue: 20, RevenueSquares: 20},
+		3: {Views: 1000, Clicks: 10, Conversions: 5, Revenue: 500, RevenueSquares: 50000},
+	} {
+		mab.AddBanner(slotID, bannerID)
+		*mab.slots[slotID].statsFor(bannerID, groupID) = stats
+	}
+
+	for goal, expected := range map[e.OptimizationGoal]e.BannerID{
+		"":                1,
+		e.GoalCTR:         1,
+		e.GoalConversions: 2,
+		e.GoalRevenue:     3,
+	} {
+		mab.SetGoal(slotID, goal)
+		if selected := mab.SelectBanner(slotID, groupID); selected != expected {
+			t.Errorf("Goal %q: expected banner %d, got %d", goal, expected, selected)
+		}
+	}
+
+	if err := mab.RecordConversion(slotID, 3, groupID, 2.5); err != nil {
+		t.Fatal(err)
+	}
+	if stats, _ := mab.GetStats(slotID, 3, groupID); stats.Conversions != 6 || stats.Revenue != 502.5 ||
+		stats.RevenueSquares != 50006.25 {
+		t.Errorf("Expected the conversion to be recorded, got %+v", stats)
+	}
+}
+
+func TestUCBV(t *testing.T) {
+	mab := NewMultiArmedBandit(make(map[e.SlotID]*Slot))
+	slotID := e.SlotID(1)
+	groupID := e.UserGroupID(1)
+
+	// Both banners earn 0.1 per impression, but banner 2 in a single large
+	// conversion, so its mean is less certain and worth exploring.
+	for bannerID, stats := range map[e.BannerID]GroupStats{
+		1: {Views: 1000, Conversions: 10, Revenue: 100, RevenueSquares: 1000},
+		2: {Views: 1000, Conversions: 1, Revenue: 100, RevenueSquares: 10000},
+	} {
+		mab.AddBanner(slotID, bannerID)
+		*mab.slots[slotID].statsFor(bannerID, groupID) = stats
+	}
+	mab.SetGoal(slotID, e.GoalRevenue)
+	mab.SetStrategy(slotID, e.StrategyUCBV)
+
+	if selected := mab.SelectBanner(slotID, groupID); selected != 2 {
+		t.Errorf("Expected the banner with the more variable revenue, got %d", selected)
+	}
+
+	// A banner without impressions is tried first.
+	mab.AddBanner(slotID, 3)
+	if selected := mab.SelectBanner(slotID, groupID); selected != 3 {
+		t.Errorf("Expected the new banner, got %d", selected)
+	}
+}
+
+func TestCalculateUCBV(t *testing.T) {
+	logTotalViews := math.Log(1000)
+
+	// Rewards of 0.5 on every impression never vary, so only the range term
+	// of the bonus is left.
+	constant := calculateUCBV(50, 25, 100, logTotalViews, 1)
+	if want := 0.5 + 3*logTotalViews/100; math.Abs(constant-want) > 1e-9 {
+		t.Errorf("Expected %v for constant rewards, got %v", want, constant)
+	}
+	// Rewards of 1 on half the impressions have the same mean but vary.
+	if variable := calculateUCBV(50, 50, 100, logTotalViews, 1); variable <= constant {
+		t.Errorf("Expected more variable rewards to get a larger index, got %v and %v", variable, constant)
+	}
+}
+
+func TestHoldout(t *testing.T) {
+	mab := NewMultiArmedBandit(make(map[e.SlotID]*Slot))
+	slotID := e.SlotID(1)
+	groupID := e.UserGroupID(1)
+
+	for bannerID := e.BannerID(1); bannerID <= 3; bannerID++ {
+		mab.AddBanner(slotID, bannerID)
+	}
+	*mab.slots[slotID].statsFor(1, groupID) = GroupStats{Views: 1000, Clicks: 500}
+	*mab.slots[slotID].statsFor(2, groupID) = GroupStats{Views: 1000}
+	*mab.slots[slotID].statsFor(3, groupID) = GroupStats{Views: 1000}
+
+	for i := 0; i < 10; i++ {
+		if selection := mab.Select(slotID, groupID, false); selection.Control || selection.BannerID != 1 {
+			t.Fatalf("Expected the bandit to pick banner 1 without a holdout, got %+v", selection)
+		}
+	}
+
+	mab.SetHoldout(slotID, 100)
+	picked := make(map[e.BannerID]int)
+	for i := 0; i < 300; i++ {
+		selection := mab.Select(slotID, groupID, false, 3)
+		if !selection.Control {
+			t.Fatalf("Expected every selection to be held out, got %+v", selection)
+		}
+		picked[selection.BannerID]++
+	}
+	if picked[1] == 0 || picked[2] == 0 || picked[3] != 0 {
+		t.Errorf("Expected control selections to spread over the banners not excluded, got %v", picked)
+	}
+}
@@ -0,0 +1,97 @@
+package frequency
+
+import (
+	"sync"
+	"time"
+
+	e "github.com/yuriiwanchev/banner-rotation-service/internal/entities"
+)
+
+// Capper limits how many times one user is shown the same banner of a slot
+// within a sliding window.
+type Capper struct {
+	limit  int
+	window time.Duration
+
+	mu        sync.Mutex
+	views     map[userKey]map[e.BannerID][]time.Time
+	lastSweep time.Time
+}
+
+type userKey struct {
+	slotID e.SlotID
+	userID string
+}
+
+// NewCapper allows limit views of a banner per user in every window.
+func NewCapper(limit int, window time.Duration) *Capper {
+	return &Capper{
+		limit:     limit,
+		window:    window,
+		views:     make(map[userKey]map[e.BannerID][]time.Time),
+		lastSweep: time.Now(),
+	}
+}
+
+// Capped returns the banners of the slot the user has reached the limit for.
+func (c *Capper) Capped(slotID e.SlotID, userID string, now time.Time) []e.BannerID {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var capped []e.BannerID
+	for bannerID, times := range c.views[userKey{slotID, userID}] {
+		if len(c.recent(times, now)) >= c.limit {
+			capped = append(capped, bannerID)
+		}
+	}
+	return capped
+}
+
+// Record counts a view of a banner by the user.
+func (c *Capper) Record(slotID e.SlotID, bannerID e.BannerID, userID string, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := userKey{slotID, userID}
+	banners, exists := c.views[key]
+	if !exists {
+		banners = make(map[e.BannerID][]time.Time)
+		c.views[key] = banners
+	}
+	// Views older than the window are dropped here, so that at most limit
+	// times are kept per banner.
+	banners[bannerID] = append(c.recent(banners[bannerID], now), now)
+	if len(banners[bannerID]) > c.limit {
+		banners[bannerID] = banners[bannerID][1:]
+	}
+
+	if now.Sub(c.lastSweep) >= c.window {
+		c.sweep(now)
+	}
+}
+
+// recent returns the suffix of times, in ascending order, within the window
+// ending at now.
+func (c *Capper) recent(times []time.Time, now time.Time) []time.Time {
+	for len(times) > 0 && !now.Before(times[0].Add(c.window)) {
+		times = times[1:]
+	}
+	return times
+}
+
+// sweep forgets users who have not been shown anything within the window, so
+// that memory is bounded by the users seen in the last window. The caller must
+// hold c.mu.
+func (c *Capper) sweep(now time.Time) {
+	for key, banners := range c.views {
+		for bannerID, times := range banners {
+			if len(c.recent(times, now)) == 0 {
+				delete(banners, bannerID)
+			}
+		}
+		if len(banners) == 0 {
+			delete(c.views, key)
+		}
+	}
+	c.lastSweep = now
+}
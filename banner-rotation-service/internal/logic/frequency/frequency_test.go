@@ -0,0 +1,48 @@
+package frequency
+
+import (
+	"testing"
+	"time"
+
+	e "github.com/yuriiwanchev/banner-rotation-service/internal/entities"
+)
+
+func TestCapper(t *testing.T) {
+	capper := NewCapper(3, time.Hour)
+	slotID := e.SlotID(1)
+	now := time.Now()
+
+	for i := 0; i < 3; i++ {
+		if capped := capper.Capped(slotID, "alice", now); len(capped) != 0 {
+			t.Fatalf("Expected no banner capped after %d views, got %v", i, capped)
+		}
+		capper.Record(slotID, 1, "alice", now.Add(time.Duration(i)*time.Minute))
+	}
+
+	if capped := capper.Capped(slotID, "alice", now.Add(3*time.Minute)); len(capped) != 1 || capped[0] != 1 {
+		t.Errorf("Expected banner 1 to be capped, got %v", capped)
+	}
+	if capped := capper.Capped(slotID, "bob", now); len(capped) != 0 {
+		t.Errorf("Expected the cap to be per user, got %v", capped)
+	}
+	if capped := capper.Capped(2, "alice", now); len(capped) != 0 {
+		t.Errorf("Expected the cap to be per slot, got %v", capped)
+	}
+
+	// The window slides: once the first view is an hour old, one more is allowed.
+	if capped := capper.Capped(slotID, "alice", now.Add(time.Hour)); len(capped) != 0 {
+		t.Errorf("Expected the oldest view to leave the window, got %v", capped)
+	}
+}
+
+func TestCapperForgetsIdleUsers(t *testing.T) {
+	capper := NewCapper(1, time.Minute)
+	now := time.Now()
+
+	capper.Record(1, 1, "alice", now)
+	capper.Record(1, 1, "bob", now.Add(2*time.Minute))
+
+	if len(capper.views) != 1 {
+		t.Errorf("Expected users idle for a whole window to be forgotten, got %d tracked", len(capper.views))
+	}
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"time"
+
 	e "github.com/yuriiwanchev/banner-rotation-service/internal/entities"
 )
 
@@ -18,13 +20,139 @@ type RecordClickRequest struct {
 	SlotID      e.SlotID      `json:"slotId"`
 	BannerID    e.BannerID    `json:"bannerId"`
 	UserGroupID e.UserGroupID `json:"userGroupId"`
+	// ImpressionID optionally identifies the clicked showing of the banner, which
+	// tells clicks on control group selections apart.
+	ImpressionID string `json:"impressionId,omitempty"`
+}
+
+type RecordConversionRequest struct {
+	SlotID      e.SlotID      `json:"slotId"`
+	BannerID    e.BannerID    `json:"bannerId"`
+	UserGroupID e.UserGroupID `json:"userGroupId"`
+	Value       float64       `json:"value,omitempty"`
 }
 
 type SelectBannerRequest struct {
 	SlotID      e.SlotID      `json:"slotId"`
 	UserGroupID e.UserGroupID `json:"userGroupId"`
+	// UserID optionally identifies the user for frequency capping. It is opaque
+	// to the service.
+	UserID string `json:"userId,omitempty"`
+}
+
+type RecordViewRequest struct {
+	ImpressionID string `json:"impressionId"`
 }
 
 type SelectBannerResponse struct {
 	BannerID e.BannerID `json:"bannerId"`
+	// Creative is omitted for banners created without one.
+	Creative *e.Creative `json:"creative,omitempty"`
+	// ImpressionID identifies this showing of the banner in click links.
+	ImpressionID string `json:"impressionId,omitempty"`
+	// Control is set if the banner was picked at random for the holdout
+	// control group; clicks on it are attributed by ImpressionID.
+	Control bool `json:"control,omitempty"`
+}
+
+type CreateBannerRequest struct {
+	Description string      `json:"description"`
+	Creative    *e.Creative `json:"creative,omitempty"`
+}
+
+type HealthResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+type ReloadResponse struct {
+	Slots   int `json:"slots"`
+	Banners int `json:"banners"`
+}
+
+type BuildInfo struct {
+	Release   string `json:"release"`
+	BuildDate string `json:"buildDate"`
+	GitHash   string `json:"gitHash"`
+}
+
+type AddSlotBannerRequest struct {
+	BannerID e.BannerID `json:"bannerId"`
+}
+
+// UpdateSlotBannerRequest replaces the schedule of a banner in a slot.
+// An empty Status means active.
+type UpdateSlotBannerRequest struct {
+	StartsAt            *time.Time     `json:"startsAt,omitempty"`
+	EndsAt              *time.Time     `json:"endsAt,omitempty"`
+	Status              e.BannerStatus `json:"status,omitempty"`
+	MinDailyImpressions int            `json:"minDailyImpressions,omitempty"`
+	MaxDailyImpressions int            `json:"maxDailyImpressions,omitempty"`
+}
+
+// BannerDelivery is the progress of a banner towards its daily impression
+// goals. RemainingImpressions is omitted for banners without a cap.
+type BannerDelivery struct {
+	BannerID             e.BannerID `json:"bannerId"`
+	Day                  string     `json:"day"`
+	MinDailyImpressions  int        `json:"minDailyImpressions"`
+	MaxDailyImpressions  int        `json:"maxDailyImpressions"`
+	Impressions          int        `json:"impressions"`
+	MissingImpressions   int        `json:"missingImpressions"`
+	RemainingImpressions *int       `json:"remainingImpressions,omitempty"`
+}
+
+type CreateSelectionRequest struct {
+	UserGroupID e.UserGroupID `json:"userGroupId"`
+	UserID      string        `json:"userId,omitempty"`
+}
+
+type CreateClickRequest struct {
+	UserGroupID  e.UserGroupID `json:"userGroupId"`
+	ImpressionID string        `json:"impressionId,omitempty"`
+}
+
+type CreateConversionRequest struct {
+	UserGroupID e.UserGroupID `json:"userGroupId"`
+	Value       float64       `json:"value,omitempty"`
+}
+
+// UpdateSlotRequest replaces every rotation setting of a slot, see UpdateSlot.
+type UpdateSlotRequest struct {
+	Goal           e.OptimizationGoal `json:"goal,omitempty"`
+	Strategy       e.RotationStrategy `json:"strategy,omitempty"`
+	HoldoutPercent int                `json:"holdoutPercent,omitempty"`
+}
+
+// TrafficStats are the views and clicks of a part of the traffic of a slot.
+type TrafficStats struct {
+	Views  int     `json:"views"`
+	Clicks int     `json:"clicks"`
+	CTR    float64 `json:"ctr"`
+}
+
+// LiftReport compares the CTR of the traffic served by the bandit with that
+// of the control group served at random. Lift and ZScore are omitted until
+// both parts have views and the control group clicks.
+type LiftReport struct {
+	SlotID         e.SlotID     `json:"slotId"`
+	HoldoutPercent int          `json:"holdoutPercent"`
+	Bandit         TrafficStats `json:"bandit"`
+	Control        TrafficStats `json:"control"`
+	// Lift is the relative CTR gain of the bandit, e.g. 0.25 for 25% more clicks per view.
+	Lift *float64 `json:"lift,omitempty"`
+	// ZScore of the difference between the CTRs; beyond 1.96 it is significant at 95%.
+	ZScore *float64 `json:"zScore,omitempty"`
+}
+
+type CreateAPIKeyRequest struct {
+	Name string `json:"name"`
+	Role e.Role `json:"role"`
+}
+
+type CreateAPIKeyResponse struct {
+	ID   e.APIKeyID `json:"id"`
+	Name string     `json:"name"`
+	Role e.Role     `json:"role"`
+	Key  string     `json:"key"`
 }
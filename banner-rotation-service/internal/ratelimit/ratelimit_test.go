@@ -0,0 +1,55 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiterAllow(t *testing.T) {
+	now := time.Unix(1000, 0)
+	l := NewLimiter(Limit{Rate: 2, Burst: 3})
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.Allow("client"); !ok {
+			t.Fatalf("Request %d within burst was rejected", i)
+		}
+	}
+
+	ok, retryAfter := l.Allow("client")
+	if ok {
+		t.Fatalf("Request over burst was allowed")
+	}
+	if retryAfter != 500*time.Millisecond {
+		t.Errorf("Expected retry after 500ms, got %v", retryAfter)
+	}
+
+	if ok, _ := l.Allow("other"); !ok {
+		t.Errorf("Buckets of different clients must be independent")
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if ok, _ := l.Allow("client"); !ok {
+		t.Errorf("Request after refill was rejected")
+	}
+}
+
+func TestParseLimits(t *testing.T) {
+	limits, err := ParseLimits("createClick=50:100, createSelection=200")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limits["createClick"] != (Limit{Rate: 50, Burst: 100}) {
+		t.Errorf("Unexpected createClick limit %+v", limits["createClick"])
+	}
+	if limits["createSelection"] != (Limit{Rate: 200, Burst: 200}) {
+		t.Errorf("Unexpected createSelection limit %+v", limits["createSelection"])
+	}
+
+	if _, err := ParseLimits("createClick"); err == nil {
+		t.Errorf("Expected error for entry without a limit")
+	}
+	if _, err := ParseLimits("createClick=x"); err == nil {
+		t.Errorf("Expected error for invalid rate")
+	}
+}
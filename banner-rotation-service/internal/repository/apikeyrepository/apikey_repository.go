@@ -0,0 +1,96 @@
+package apikeyrepository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	e "github.com/yuriiwanchev/banner-rotation-service/internal/entities"
+	"github.com/yuriiwanchev/banner-rotation-service/internal/repository"
+)
+
+type APIKeyRepository interface {
+	CreateAPIKey(ctx context.Context, key *e.APIKey, keyHash string) (e.APIKeyID, error)
+	GetAPIKeyByHash(ctx context.Context, keyHash string) (*e.APIKey, error)
+	GetAllAPIKeys(ctx context.Context) ([]*e.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id e.APIKeyID) error
+}
+
+type PgAPIKeyRepository struct {
+	DB *sql.DB
+}
+
+func (r *PgAPIKeyRepository) CreateAPIKey(ctx context.Context, key *e.APIKey, keyHash string) (e.APIKeyID, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx)
+	defer cancel()
+
+	var id e.APIKeyID
+	err := r.DB.QueryRowContext(ctx, "INSERT INTO api_keys (name, role, key_hash) VALUES ($1, $2, $3) RETURNING id",
+		key.Name, key.Role, keyHash).Scan(&id)
+	if err != nil {
+		return 0, repository.WrapError(err)
+	}
+	return id, nil
+}
+
+// GetAPIKeyByHash returns the key with the given hash unless it was revoked.
+func (r *PgAPIKeyRepository) GetAPIKeyByHash(ctx context.Context, keyHash string) (*e.APIKey, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx)
+	defer cancel()
+
+	sql := `SELECT id, name, role, created_at
+			FROM api_keys
+			WHERE key_hash = $1 AND revoked_at IS NULL`
+
+	key := &e.APIKey{}
+	err := r.DB.QueryRowContext(ctx, sql, keyHash).Scan(&key.ID, &key.Name, &key.Role, &key.CreatedAt)
+	if err != nil {
+		return nil, repository.WrapError(err)
+	}
+	return key, nil
+}
+
+func (r *PgAPIKeyRepository) GetAllAPIKeys(ctx context.Context) ([]*e.APIKey, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx)
+	defer cancel()
+
+	sql := `SELECT id, name, role, created_at, revoked_at FROM api_keys ORDER BY id`
+	rows, err := r.DB.QueryContext(ctx, sql)
+	if err != nil {
+		return nil, repository.WrapError(err)
+	}
+	defer rows.Close()
+
+	var keys []*e.APIKey
+	for rows.Next() {
+		key := &e.APIKey{}
+		if err := rows.Scan(&key.ID, &key.Name, &key.Role, &key.CreatedAt, &key.RevokedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan api key: %w", repository.WrapError(err))
+		}
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error occurred during row iteration: %w", repository.WrapError(err))
+	}
+
+	return keys, nil
+}
+
+func (r *PgAPIKeyRepository) RevokeAPIKey(ctx context.Context, id e.APIKeyID) error {
+	ctx, cancel := repository.WithQueryTimeout(ctx)
+	defer cancel()
+
+	result, err := r.DB.ExecContext(ctx, "UPDATE api_keys SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL", id)
+	if err != nil {
+		return repository.WrapError(err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
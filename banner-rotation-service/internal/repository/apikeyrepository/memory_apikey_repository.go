@@ -0,0 +1,94 @@
+package apikeyrepository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	e "github.com/yuriiwanchev/banner-rotation-service/internal/entities"
+)
+
+var (
+	_ APIKeyRepository = (*PgAPIKeyRepository)(nil)
+	_ APIKeyRepository = (*MemAPIKeyRepository)(nil)
+)
+
+type memAPIKey struct {
+	key  e.APIKey
+	hash string
+}
+
+// MemAPIKeyRepository is a thread-safe in-memory APIKeyRepository.
+type MemAPIKeyRepository struct {
+	keys   map[e.APIKeyID]*memAPIKey
+	nextID e.APIKeyID
+	mu     sync.RWMutex
+}
+
+func NewMemAPIKeyRepository() *MemAPIKeyRepository {
+	return &MemAPIKeyRepository{
+		keys:   make(map[e.APIKeyID]*memAPIKey),
+		nextID: 1,
+	}
+}
+
+func (r *MemAPIKeyRepository) CreateAPIKey(_ context.Context, key *e.APIKey, keyHash string) (e.APIKeyID, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, stored := range r.keys {
+		if stored.hash == keyHash {
+			return 0, errors.New("api key hash already exists")
+		}
+	}
+
+	id := r.nextID
+	r.nextID++
+	r.keys[id] = &memAPIKey{
+		key:  e.APIKey{ID: id, Name: key.Name, Role: key.Role, CreatedAt: time.Now()},
+		hash: keyHash,
+	}
+	return id, nil
+}
+
+func (r *MemAPIKeyRepository) GetAPIKeyByHash(_ context.Context, keyHash string) (*e.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, stored := range r.keys {
+		if stored.hash == keyHash && stored.key.RevokedAt == nil {
+			key := stored.key
+			return &key, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *MemAPIKeyRepository) GetAllAPIKeys(_ context.Context) ([]*e.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	keys := make([]*e.APIKey, 0, len(r.keys))
+	for _, stored := range r.keys {
+		key := stored.key
+		keys = append(keys, &key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+	return keys, nil
+}
+
+func (r *MemAPIKeyRepository) RevokeAPIKey(_ context.Context, id e.APIKeyID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, exists := r.keys[id]
+	if !exists || stored.key.RevokedAt != nil {
+		return sql.ErrNoRows
+	}
+	now := time.Now()
+	stored.key.RevokedAt = &now
+	return nil
+}
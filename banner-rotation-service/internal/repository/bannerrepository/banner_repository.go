@@ -1,6 +1,7 @@
 package bannerrepository
 
 import (
+	"context"
 	"database/sql"
 
 	e "github.com/yuriiwanchev/banner-rotation-service/internal/entities"
@@ -8,32 +9,64 @@ import (
 )
 
 type BannerRepository interface {
-	GetBannerByID(id e.BannerID) (*e.Banner, error)
-	CreateBanner(banner *e.Banner) (e.BannerID, error)
+	GetBannerByID(ctx context.Context, id e.BannerID) (*e.Banner, error)
+	CreateBanner(ctx context.Context, banner *e.Banner) (e.BannerID, error)
 }
 
 type PgBannerRepository struct {
 	DB *sql.DB
 }
 
-func (r *PgBannerRepository) GetBannerByID(id e.BannerID) (*e.Banner, error) {
-	db := repository.GetDB()
+func (r *PgBannerRepository) GetBannerByID(ctx context.Context, id e.BannerID) (*e.Banner, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx)
+	defer cancel()
 
 	banner := &e.Banner{}
-	err := db.QueryRow("SELECT id, description FROM banners WHERE id = $1", id).Scan(&banner.ID, &banner.Description)
+	var (
+		imageURL, altText, targetURL, format sql.NullString
+		width, height                        sql.NullInt64
+	)
+	err := r.DB.QueryRowContext(ctx, `SELECT id, description, image_url, alt_text, target_url, width, height, format 
+			FROM banners 
+			WHERE id = $1`, id).
+		Scan(&banner.ID, &banner.Description, &imageURL, &altText, &targetURL, &width, &height, &format)
 	if err != nil {
-		return nil, err
+		return nil, repository.WrapError(err)
+	}
+
+	if imageURL.Valid {
+		banner.Creative = &e.Creative{
+			ImageURL:  imageURL.String,
+			AltText:   altText.String,
+			TargetURL: targetURL.String,
+			Width:     int(width.Int64),
+			Height:    int(height.Int64),
+			Format:    e.CreativeFormat(format.String),
+		}
 	}
 	return banner, nil
 }
 
-func (r *PgBannerRepository) CreateBanner(banner *e.Banner) (e.BannerID, error) {
-	db := repository.GetDB()
+func (r *PgBannerRepository) CreateBanner(ctx context.Context, banner *e.Banner) (e.BannerID, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx)
+	defer cancel()
+
+	// Without a creative all of its columns stay NULL.
+	var imageURL, altText, targetURL, format, width, height interface{}
+	if c := banner.Creative; c != nil {
+		imageURL, altText, targetURL, format, width, height = c.ImageURL, c.AltText, c.TargetURL, string(c.Format),
+			c.Width, c.Height
+	}
 
 	var id e.BannerID
-	err := db.QueryRow("INSERT INTO banners (description) VALUES ($1) RETURNING id", banner.Description).Scan(&id)
+	err := r.DB.QueryRowContext(ctx, `INSERT INTO banners 
+			(description, image_url, alt_text, target_url, width, height, format) 
+			VALUES ($1, $2, $3, $4, $5, $6, $7) 
+			RETURNING id`,
+		banner.Description, imageURL, altText, targetURL, width, height, format).
+		Scan(&id)
 	if err != nil {
-		return 0, err
+		return 0, repository.WrapError(err)
 	}
 	return id, nil
 }
@@ -0,0 +1,66 @@
+package bannerrepository
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	e "github.com/yuriiwanchev/banner-rotation-service/internal/entities"
+)
+
+var (
+	_ BannerRepository = (*PgBannerRepository)(nil)
+	_ BannerRepository = (*MemBannerRepository)(nil)
+)
+
+// MemBannerRepository is a thread-safe in-memory BannerRepository.
+type MemBannerRepository struct {
+	banners map[e.BannerID]e.Banner
+	nextID  e.BannerID
+	mu      sync.RWMutex
+}
+
+func NewMemBannerRepository() *MemBannerRepository {
+	return &MemBannerRepository{
+		banners: make(map[e.BannerID]e.Banner),
+		nextID:  1,
+	}
+}
+
+func (r *MemBannerRepository) GetBannerByID(_ context.Context, id e.BannerID) (*e.Banner, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	banner, exists := r.banners[id]
+	if !exists {
+		return nil, sql.ErrNoRows
+	}
+	if banner.Creative != nil {
+		creative := *banner.Creative
+		banner.Creative = &creative
+	}
+	return &banner, nil
+}
+
+// CreateBanner stores the banner under banner.ID if it is set, or under the next free ID.
+func (r *MemBannerRepository) CreateBanner(_ context.Context, banner *e.Banner) (e.BannerID, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := banner.ID
+	if id == 0 {
+		id = r.nextID
+	}
+	if id >= r.nextID {
+		r.nextID = id + 1
+	}
+
+	stored := *banner
+	stored.ID = id
+	if banner.Creative != nil {
+		creative := *banner.Creative
+		stored.Creative = &creative
+	}
+	r.banners[id] = stored
+	return id, nil
+}
@@ -1,33 +1,72 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"log"
+	"math/rand/v2"
+	"sync/atomic"
 	"time"
 
-	// Register some standard stuff.
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
-var db *sql.DB
+// db is published atomically because /readyz and /metrics are served while
+// InitDB is still connecting.
+var db atomic.Pointer[sql.DB]
+
+// DBConfig configures the connection pool and how InitDB waits for the
+// database to come up.
+type DBConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+
+	ConnectAttempts int
+	InitialBackoff  time.Duration
+	MaxBackoff      time.Duration
+}
+
+func DefaultDBConfig() DBConfig {
+	return DBConfig{
+		MaxOpenConns:    25,
+		MaxIdleConns:    25,
+		ConnMaxLifetime: 30 * time.Minute,
+		ConnMaxIdleTime: 5 * time.Minute,
+		ConnectAttempts: 10,
+		InitialBackoff:  500 * time.Millisecond,
+		MaxBackoff:      30 * time.Second,
+	}
+}
 
-func InitDB(connStr string) {
+func InitDB(connStr string, config DBConfig) {
 	log.Println("Connecting to the database...")
-	var err error
-	for i := 0; i < 10; i++ {
-		db, err = sql.Open("postgres", connStr)
-		if err != nil {
-			log.Println(err)
-			log.Println("Failed to connect to database, retrying in 5 seconds...")
-			time.Sleep(2 * time.Second)
-			continue
-		}
-		if err = db.Ping(); err == nil {
+
+	conn, err := sql.Open("postgres", connStr)
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+
+	conn.SetMaxOpenConns(config.MaxOpenConns)
+	conn.SetMaxIdleConns(config.MaxIdleConns)
+	conn.SetConnMaxLifetime(config.ConnMaxLifetime)
+	conn.SetConnMaxIdleTime(config.ConnMaxIdleTime)
+	db.Store(conn)
+
+	attempts := max(config.ConnectAttempts, 1)
+	for attempt := 0; attempt < attempts; attempt++ {
+		if err = conn.Ping(); err == nil {
 			break
 		}
 
-		log.Println("Database not ready, retrying in 2 seconds...")
-		time.Sleep(1 * time.Second)
+		if attempt+1 < attempts {
+			delay := backoff(attempt, config.InitialBackoff, config.MaxBackoff)
+			log.Printf("Database not ready (%v), retrying in %v...", err, delay.Round(time.Millisecond))
+			time.Sleep(delay)
+		}
 	}
 
 	if err != nil {
@@ -37,81 +76,62 @@ func InitDB(connStr string) {
 	log.Println("Connected to the database successfully")
 }
 
+// backoff returns the delay before retry number attempt: the initial delay
+// doubled per attempt and capped at maxDelay, with its upper half randomized
+// so that replicas restarted together do not retry in lockstep.
+func backoff(attempt int, initial, maxDelay time.Duration) time.Duration {
+	delay := maxDelay
+	if attempt < 32 && initial<<attempt > 0 && initial<<attempt < maxDelay {
+		delay = initial << attempt
+	}
+	if delay < 2 {
+		return delay
+	}
+	half := delay / 2
+	return half + rand.N(delay-half)
+}
+
 func GetDB() *sql.DB {
-	return db
+	return db.Load()
 }
 
 func CloseDB() {
-	db.Close()
+	db.Load().Close()
 }
 
-func CheckTablesExist() (bool, error) {
-	query := `
-    SELECT EXISTS (
-        SELECT 1 
-        FROM information_schema.tables 
-        WHERE table_name = 'slots'
-        OR table_name = 'banners'
-        OR table_name = 'user_groups'
-		OR table_name = 'slots_banners'
-        OR table_name = 'statistics'
-    );
-    `
-	var exists bool
-	err := db.QueryRow(query).Scan(&exists)
-	if err != nil {
-		return false, err
-	}
-	return exists, nil
+// ErrQueryTimeout is returned by repositories when a query did not finish
+// within the query timeout or its context deadline.
+var ErrQueryTimeout = errors.New("database query timed out")
+
+const defaultQueryTimeout = 3 * time.Second
+
+var queryTimeout atomic.Int64
+
+func init() {
+	queryTimeout.Store(int64(defaultQueryTimeout))
 }
 
-func InitSchema() {
-	exists, err := CheckTablesExist()
-	if err != nil {
-		log.Fatalf("Failed to check if tables exist: %v", err)
-	}
+// SetQueryTimeout sets the deadline applied to every repository query.
+func SetQueryTimeout(timeout time.Duration) {
+	queryTimeout.Store(int64(timeout))
+}
 
-	if exists {
-		log.Println("Database schema already initialized, skipping")
-		return
-	}
+// WithQueryTimeout derives the context a single query runs with.
+func WithQueryTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, time.Duration(queryTimeout.Load()))
+}
 
-	schema := `
-    CREATE TABLE IF NOT EXISTS slots (
-        id SERIAL PRIMARY KEY,
-        description TEXT NOT NULL
-    );
-
-    CREATE TABLE IF NOT EXISTS banners (
-        id SERIAL PRIMARY KEY,
-        description TEXT NOT NULL
-    );
-
-    CREATE TABLE IF NOT EXISTS user_groups (
-        id SERIAL PRIMARY KEY,
-        description TEXT NOT NULL
-    );
-
-	CREATE TABLE IF NOT EXISTS slot_banners (
-    slot_id INT NOT NULL REFERENCES slots(id),
-    banner_id INT NOT NULL REFERENCES banners(id),
-    PRIMARY KEY (slot_id, banner_id)
-	);
-
-    CREATE TABLE IF NOT EXISTS statistics (
-        id SERIAL PRIMARY KEY,
-        slot_id INT NOT NULL REFERENCES slots(id),
-        banner_id INT NOT NULL REFERENCES banners(id),
-        user_group_id INT NOT NULL REFERENCES user_groups(id),
-        clicks INT DEFAULT 0,
-        views INT DEFAULT 0
-    );
-    `
-
-	_, err = db.Exec(schema)
-	if err != nil {
-		log.Fatalf("Failed to initialize database schema: %v", err)
+// WrapError turns deadline and cancellation failures into ErrQueryTimeout,
+// leaving other errors untouched.
+func WrapError(err error) error {
+	if err == nil {
+		return nil
 	}
 
-	log.Println("Database schema initialized successfully")
+	var pqErr *pq.Error
+	if errors.Is(err, context.DeadlineExceeded) ||
+		(errors.As(err, &pqErr) && pqErr.Code == "57014") { // query_canceled
+		return fmt.Errorf("%w: %w", ErrQueryTimeout, err)
+	}
+	return err
 }
@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+func TestWrapError(t *testing.T) {
+	tests := []struct {
+		err     error
+		timeout bool
+	}{
+		{context.DeadlineExceeded, true},
+		{&pq.Error{Code: "57014"}, true},
+		{&pq.Error{Code: "23505"}, false},
+		{sql.ErrNoRows, false},
+	}
+
+	for _, tt := range tests {
+		err := WrapError(tt.err)
+		if errors.Is(err, ErrQueryTimeout) != tt.timeout {
+			t.Errorf("WrapError(%v): expected timeout %v, got %v", tt.err, tt.timeout, err)
+		}
+		if !errors.Is(err, tt.err) {
+			t.Errorf("WrapError(%v) must keep the original error, got %v", tt.err, err)
+		}
+	}
+
+	if WrapError(nil) != nil {
+		t.Error("WrapError(nil) must be nil")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	initial, maxDelay := 100*time.Millisecond, time.Second
+
+	for attempt := 0; attempt < 70; attempt++ {
+		ceiling := maxDelay
+		if attempt < 4 {
+			ceiling = initial << attempt
+		}
+		for i := 0; i < 20; i++ {
+			delay := backoff(attempt, initial, maxDelay)
+			if delay < ceiling/2 || delay >= ceiling {
+				t.Fatalf("backoff(%d) = %v, expected within [%v, %v)", attempt, delay, ceiling/2, ceiling)
+			}
+		}
+	}
+}
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS statistics;
+DROP TABLE IF EXISTS slot_banners;
+DROP TABLE IF EXISTS user_groups;
+DROP TABLE IF EXISTS banners;
+DROP TABLE IF EXISTS slots;
@@ -0,0 +1,31 @@
+-- Tables created by the former InitSchema. IF NOT EXISTS lets databases
+-- initialized before migrations were introduced adopt this version as is.
+CREATE TABLE IF NOT EXISTS slots (
+    id SERIAL PRIMARY KEY,
+    description TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS banners (
+    id SERIAL PRIMARY KEY,
+    description TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS user_groups (
+    id SERIAL PRIMARY KEY,
+    description TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS slot_banners (
+    slot_id INT NOT NULL REFERENCES slots(id),
+    banner_id INT NOT NULL REFERENCES banners(id),
+    PRIMARY KEY (slot_id, banner_id)
+);
+
+CREATE TABLE IF NOT EXISTS statistics (
+    id SERIAL PRIMARY KEY,
+    slot_id INT NOT NULL REFERENCES slots(id),
+    banner_id INT NOT NULL REFERENCES banners(id),
+    user_group_id INT NOT NULL REFERENCES user_groups(id),
+    clicks INT DEFAULT 0,
+    views INT DEFAULT 0
+);
@@ -0,0 +1,4 @@
+ALTER TABLE slot_banners
+    DROP COLUMN IF EXISTS status,
+    DROP COLUMN IF EXISTS ends_at,
+    DROP COLUMN IF EXISTS starts_at;
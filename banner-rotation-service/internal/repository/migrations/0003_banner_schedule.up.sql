@@ -0,0 +1,4 @@
+ALTER TABLE slot_banners
+    ADD COLUMN starts_at TIMESTAMPTZ,
+    ADD COLUMN ends_at TIMESTAMPTZ,
+    ADD COLUMN status TEXT NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'paused'));
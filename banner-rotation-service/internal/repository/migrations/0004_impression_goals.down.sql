@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS daily_impressions;
+
+ALTER TABLE slot_banners
+    DROP COLUMN IF EXISTS max_daily_impressions,
+    DROP COLUMN IF EXISTS min_daily_impressions;
@@ -0,0 +1,12 @@
+ALTER TABLE slot_banners
+    ADD COLUMN min_daily_impressions INT NOT NULL DEFAULT 0 CHECK (min_daily_impressions >= 0),
+    ADD COLUMN max_daily_impressions INT NOT NULL DEFAULT 0 CHECK (max_daily_impressions >= 0);
+
+-- Views per banner in a slot for each UTC day, across all user groups.
+CREATE TABLE daily_impressions (
+    slot_id INT NOT NULL REFERENCES slots(id),
+    banner_id INT NOT NULL REFERENCES banners(id),
+    day DATE NOT NULL,
+    impressions INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (slot_id, banner_id, day)
+);
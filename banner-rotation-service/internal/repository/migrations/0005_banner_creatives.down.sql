@@ -0,0 +1,7 @@
+ALTER TABLE banners
+    DROP COLUMN IF EXISTS format,
+    DROP COLUMN IF EXISTS height,
+    DROP COLUMN IF EXISTS width,
+    DROP COLUMN IF EXISTS target_url,
+    DROP COLUMN IF EXISTS alt_text,
+    DROP COLUMN IF EXISTS image_url;
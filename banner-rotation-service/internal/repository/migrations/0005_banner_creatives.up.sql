@@ -0,0 +1,8 @@
+-- A banner has a creative when image_url is set; the other columns are then set too.
+ALTER TABLE banners
+    ADD COLUMN image_url TEXT,
+    ADD COLUMN alt_text TEXT,
+    ADD COLUMN target_url TEXT,
+    ADD COLUMN width INT CHECK (width > 0),
+    ADD COLUMN height INT CHECK (height > 0),
+    ADD COLUMN format TEXT CHECK (format IN ('png', 'jpeg', 'gif', 'webp', 'svg'));
@@ -0,0 +1,6 @@
+ALTER TABLE slots
+    DROP COLUMN IF EXISTS goal;
+
+ALTER TABLE statistics
+    DROP COLUMN IF EXISTS revenue,
+    DROP COLUMN IF EXISTS conversions;
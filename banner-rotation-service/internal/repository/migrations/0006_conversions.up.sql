@@ -0,0 +1,6 @@
+ALTER TABLE statistics
+    ADD COLUMN conversions INT NOT NULL DEFAULT 0,
+    ADD COLUMN revenue DOUBLE PRECISION NOT NULL DEFAULT 0;
+
+ALTER TABLE slots
+    ADD COLUMN goal TEXT NOT NULL DEFAULT 'ctr' CHECK (goal IN ('ctr', 'conversions', 'revenue'));
@@ -0,0 +1,5 @@
+ALTER TABLE slots
+    DROP COLUMN IF EXISTS strategy;
+
+ALTER TABLE statistics
+    DROP COLUMN IF EXISTS revenue_squares;
@@ -0,0 +1,6 @@
+-- Sum of the squared conversion values, for the variance of the revenue per impression.
+ALTER TABLE statistics
+    ADD COLUMN revenue_squares DOUBLE PRECISION NOT NULL DEFAULT 0;
+
+ALTER TABLE slots
+    ADD COLUMN strategy TEXT NOT NULL DEFAULT 'ucb1' CHECK (strategy IN ('ucb1', 'ucb-v'));
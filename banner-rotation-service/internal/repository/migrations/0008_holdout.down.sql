@@ -0,0 +1,6 @@
+ALTER TABLE slots
+    DROP COLUMN IF EXISTS holdout_percent;
+
+ALTER TABLE statistics
+    DROP COLUMN IF EXISTS control_clicks,
+    DROP COLUMN IF EXISTS control_views;
@@ -0,0 +1,7 @@
+-- Views and clicks of the holdout control group, also counted in views and clicks.
+ALTER TABLE statistics
+    ADD COLUMN control_views INT NOT NULL DEFAULT 0,
+    ADD COLUMN control_clicks INT NOT NULL DEFAULT 0;
+
+ALTER TABLE slots
+    ADD COLUMN holdout_percent INT NOT NULL DEFAULT 0 CHECK (holdout_percent BETWEEN 0 AND 100);
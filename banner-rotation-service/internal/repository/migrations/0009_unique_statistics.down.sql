@@ -0,0 +1 @@
+DROP INDEX IF EXISTS statistics_slot_banner_user_group_idx;
@@ -0,0 +1,10 @@
+-- Re-adding a banner to a slot used to insert a second statistics row per user
+-- group. Increments update every matching row, so the oldest one has all counts.
+DELETE FROM statistics newer
+    USING statistics older
+    WHERE newer.slot_id = older.slot_id
+      AND newer.banner_id = older.banner_id
+      AND newer.user_group_id = older.user_group_id
+      AND newer.id > older.id;
+
+CREATE UNIQUE INDEX statistics_slot_banner_user_group_idx ON statistics (slot_id, banner_id, user_group_id);
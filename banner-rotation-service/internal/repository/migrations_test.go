@@ -0,0 +1,23 @@
+package repository
+
+import "testing"
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(migrations) == 0 {
+		t.Fatal("Expected embedded migrations")
+	}
+
+	for i, migration := range migrations {
+		if migration.Version != i+1 {
+			t.Errorf("Migration versions must be contiguous from 1, got %d at position %d", migration.Version, i)
+		}
+		if migration.Name == "" {
+			t.Errorf("Migration %d has no name", migration.Version)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package slotbannersrepository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"sync"
+
+	e "github.com/yuriiwanchev/banner-rotation-service/internal/entities"
+)
+
+var (
+	_ SlotBannerRepository = (*PgSlotBannerRepository)(nil)
+	_ SlotBannerRepository = (*MemSlotBannerRepository)(nil)
+)
+
+// MemSlotBannerRepository is a thread-safe in-memory SlotBannerRepository.
+// Like the slot_banners primary key, it rejects adding a banner twice.
+type MemSlotBannerRepository struct {
+	slotBanners map[e.SlotID]map[e.BannerID]e.SlotBanner
+	mu          sync.RWMutex
+}
+
+func NewMemSlotBannerRepository() *MemSlotBannerRepository {
+	return &MemSlotBannerRepository{
+		slotBanners: make(map[e.SlotID]map[e.BannerID]e.SlotBanner),
+	}
+}
+
+func (r *MemSlotBannerRepository) AddBannerToSlot(_ context.Context, slotID e.SlotID, bannerID e.BannerID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	banners, exists := r.slotBanners[slotID]
+	if !exists {
+		banners = make(map[e.BannerID]e.SlotBanner)
+		r.slotBanners[slotID] = banners
+	}
+
+	if _, exists := banners[bannerID]; exists {
+		return fmt.Errorf("banner %d is already in slot %d", bannerID, slotID)
+	}
+	banners[bannerID] = e.SlotBanner{SlotID: slotID, BannerID: bannerID, Status: e.BannerActive}
+	return nil
+}
+
+func (r *MemSlotBannerRepository) RemoveBannerFromSlot(_ context.Context, slotID e.SlotID, bannerID e.BannerID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.slotBanners[slotID], bannerID)
+	return nil
+}
+
+func (r *MemSlotBannerRepository) GetBannersForSlot(_ context.Context, slotID e.SlotID) ([]*e.Banner, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	banners := make([]*e.Banner, 0, len(r.slotBanners[slotID]))
+	for bannerID := range r.slotBanners[slotID] {
+		banners = append(banners, &e.Banner{ID: bannerID})
+	}
+	sort.Slice(banners, func(i, j int) bool { return banners[i].ID < banners[j].ID })
+	return banners, nil
+}
+
+func (r *MemSlotBannerRepository) GetSlotBanners(_ context.Context, slotID e.SlotID) ([]*e.SlotBanner, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	slotBanners := make([]*e.SlotBanner, 0, len(r.slotBanners[slotID]))
+	for _, sb := range r.slotBanners[slotID] {
+		sbCopy := sb
+		slotBanners = append(slotBanners, &sbCopy)
+	}
+	sort.Slice(slotBanners, func(i, j int) bool { return slotBanners[i].BannerID < slotBanners[j].BannerID })
+	return slotBanners, nil
+}
+
+func (r *MemSlotBannerRepository) UpdateSlotBanner(_ context.Context, slotBanner *e.SlotBanner) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.slotBanners[slotBanner.SlotID][slotBanner.BannerID]; !exists {
+		return sql.ErrNoRows
+	}
+	r.slotBanners[slotBanner.SlotID][slotBanner.BannerID] = *slotBanner
+	return nil
+}
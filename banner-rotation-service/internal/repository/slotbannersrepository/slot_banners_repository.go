@@ -1,6 +1,7 @@
 package slotbannersrepository
 
 import (
+	"context"
 	"database/sql"
 
 	e "github.com/yuriiwanchev/banner-rotation-service/internal/entities"
@@ -8,38 +9,46 @@ import (
 )
 
 type SlotBannerRepository interface {
-	AddBannerToSlot(slotID e.SlotID, bannerID e.BannerID) error
-	RemoveBannerFromSlot(slotID e.SlotID, bannerID e.BannerID) error
-	GetBannersForSlot(slotID e.SlotID) ([]*e.Banner, error)
+	AddBannerToSlot(ctx context.Context, slotID e.SlotID, bannerID e.BannerID) error
+	RemoveBannerFromSlot(ctx context.Context, slotID e.SlotID, bannerID e.BannerID) error
+	GetBannersForSlot(ctx context.Context, slotID e.SlotID) ([]*e.Banner, error)
+	GetSlotBanners(ctx context.Context, slotID e.SlotID) ([]*e.SlotBanner, error)
+	UpdateSlotBanner(ctx context.Context, slotBanner *e.SlotBanner) error
 }
 
 type PgSlotBannerRepository struct {
 	DB *sql.DB
 }
 
-func (r *PgSlotBannerRepository) AddBannerToSlot(slotID e.SlotID, bannerID e.BannerID) error {
-	db := repository.GetDB()
+func (r *PgSlotBannerRepository) AddBannerToSlot(ctx context.Context, slotID e.SlotID, bannerID e.BannerID) error {
+	ctx, cancel := repository.WithQueryTimeout(ctx)
+	defer cancel()
 
-	_, err := db.Exec("INSERT INTO slot_banners (slot_id, banner_id) VALUES ($1, $2)", slotID, bannerID)
-	return err
+	_, err := r.DB.ExecContext(ctx, "INSERT INTO slot_banners (slot_id, banner_id) VALUES ($1, $2)", slotID, bannerID)
+	return repository.WrapError(err)
 }
 
-func (r *PgSlotBannerRepository) RemoveBannerFromSlot(slotID e.SlotID, bannerID e.BannerID) error {
-	_, err := r.DB.Exec("DELETE FROM slot_banners WHERE slot_id = $1 AND banner_id = $2", slotID, bannerID)
-	return err
+func (r *PgSlotBannerRepository) RemoveBannerFromSlot(ctx context.Context, slotID e.SlotID,
+	bannerID e.BannerID,
+) error {
+	ctx, cancel := repository.WithQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := r.DB.ExecContext(ctx, "DELETE FROM slot_banners WHERE slot_id = $1 AND banner_id = $2", slotID, bannerID)
+	return repository.WrapError(err)
 }
 
-func (r *PgSlotBannerRepository) GetBannersForSlot(slotID e.SlotID) ([]*e.Banner, error) {
+func (r *PgSlotBannerRepository) GetBannersForSlot(ctx context.Context, slotID e.SlotID) ([]*e.Banner, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx)
+	defer cancel()
+
 	sql := `SELECT b.id, b.description 
 			FROM banners b 
 			INNER JOIN slot_banners sb ON b.id = sb.banner_id 
 			WHERE sb.slot_id = $1`
-	rows, err := r.DB.Query(sql, slotID)
-	if rows.Err() != nil {
-		return nil, rows.Err()
-	}
+	rows, err := r.DB.QueryContext(ctx, sql, slotID)
 	if err != nil {
-		return nil, err
+		return nil, repository.WrapError(err)
 	}
 	defer rows.Close()
 
@@ -47,10 +56,71 @@ func (r *PgSlotBannerRepository) GetBannersForSlot(slotID e.SlotID) ([]*e.Banner
 	for rows.Next() {
 		banner := &e.Banner{}
 		if err := rows.Scan(&banner.ID, &banner.Description); err != nil {
-			return nil, err
+			return nil, repository.WrapError(err)
 		}
 		banners = append(banners, banner)
 	}
 
+	if err := rows.Err(); err != nil {
+		return nil, repository.WrapError(err)
+	}
+
 	return banners, nil
 }
+
+// GetSlotBanners returns the memberships of a slot with their schedules and goals.
+func (r *PgSlotBannerRepository) GetSlotBanners(ctx context.Context, slotID e.SlotID) ([]*e.SlotBanner, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx)
+	defer cancel()
+
+	sql := `SELECT slot_id, banner_id, starts_at, ends_at, status, min_daily_impressions, max_daily_impressions 
+			FROM slot_banners 
+			WHERE slot_id = $1 
+			ORDER BY banner_id`
+	rows, err := r.DB.QueryContext(ctx, sql, slotID)
+	if err != nil {
+		return nil, repository.WrapError(err)
+	}
+	defer rows.Close()
+
+	var slotBanners []*e.SlotBanner
+	for rows.Next() {
+		sb := &e.SlotBanner{}
+		err := rows.Scan(&sb.SlotID, &sb.BannerID, &sb.StartsAt, &sb.EndsAt, &sb.Status,
+			&sb.MinDailyImpressions, &sb.MaxDailyImpressions)
+		if err != nil {
+			return nil, repository.WrapError(err)
+		}
+		slotBanners = append(slotBanners, sb)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, repository.WrapError(err)
+	}
+
+	return slotBanners, nil
+}
+
+// UpdateSlotBanner stores the schedule, status and goals of a membership. It returns
+// sql.ErrNoRows if the banner is not in the slot.
+func (r *PgSlotBannerRepository) UpdateSlotBanner(ctx context.Context, slotBanner *e.SlotBanner) error {
+	ctx, cancel := repository.WithQueryTimeout(ctx)
+	defer cancel()
+
+	result, err := r.DB.ExecContext(ctx, `UPDATE slot_banners 
+			SET starts_at = $3, ends_at = $4, status = $5, min_daily_impressions = $6, max_daily_impressions = $7 
+			WHERE slot_id = $1 AND banner_id = $2`,
+		slotBanner.SlotID, slotBanner.BannerID, slotBanner.StartsAt, slotBanner.EndsAt, slotBanner.Status,
+		slotBanner.MinDailyImpressions, slotBanner.MaxDailyImpressions)
+	if err != nil {
+		return repository.WrapError(err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return repository.WrapError(err)
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
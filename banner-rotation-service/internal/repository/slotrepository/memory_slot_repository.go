@@ -0,0 +1,93 @@
+package slotrepository
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"sync"
+
+	e "github.com/yuriiwanchev/banner-rotation-service/internal/entities"
+)
+
+var (
+	_ SlotRepository = (*PgSlotRepository)(nil)
+	_ SlotRepository = (*MemSlotRepository)(nil)
+)
+
+// MemSlotRepository is a thread-safe in-memory SlotRepository.
+type MemSlotRepository struct {
+	slots  map[e.SlotID]e.Slot
+	nextID e.SlotID
+	mu     sync.RWMutex
+}
+
+func NewMemSlotRepository() *MemSlotRepository {
+	return &MemSlotRepository{
+		slots:  make(map[e.SlotID]e.Slot),
+		nextID: 1,
+	}
+}
+
+func (r *MemSlotRepository) GetSlotByID(_ context.Context, id e.SlotID) (*e.Slot, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	slot, exists := r.slots[id]
+	if !exists {
+		return nil, sql.ErrNoRows
+	}
+	return &slot, nil
+}
+
+// CreateSlot stores the slot under slot.ID if it is set, or under the next free ID.
+func (r *MemSlotRepository) CreateSlot(_ context.Context, slot *e.Slot) (e.SlotID, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := slot.ID
+	if id == 0 {
+		id = r.nextID
+	}
+	if id >= r.nextID {
+		r.nextID = id + 1
+	}
+
+	stored := *slot
+	stored.ID = id
+	if stored.Goal == "" {
+		stored.Goal = e.GoalCTR
+	}
+	if stored.Strategy == "" {
+		stored.Strategy = e.StrategyUCB1
+	}
+	r.slots[id] = stored
+	return id, nil
+}
+
+func (r *MemSlotRepository) GetAllSlots(_ context.Context) ([]*e.Slot, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	slots := make([]*e.Slot, 0, len(r.slots))
+	for _, slot := range r.slots {
+		slot := slot
+		slots = append(slots, &slot)
+	}
+	sort.Slice(slots, func(i, j int) bool { return slots[i].ID < slots[j].ID })
+	return slots, nil
+}
+
+func (r *MemSlotRepository) UpdateSlotRotation(_ context.Context, slot *e.Slot) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, exists := r.slots[slot.ID]
+	if !exists {
+		return sql.ErrNoRows
+	}
+	stored.Goal = slot.Goal
+	stored.Strategy = slot.Strategy
+	stored.HoldoutPercent = slot.HoldoutPercent
+	r.slots[slot.ID] = stored
+	return nil
+}
@@ -1,58 +1,98 @@
 package slotrepository
 
 import (
+	"context"
 	"database/sql"
 
 	e "github.com/yuriiwanchev/banner-rotation-service/internal/entities"
+	"github.com/yuriiwanchev/banner-rotation-service/internal/repository"
 )
 
 type SlotRepository interface {
-	GetSlotByID(id e.SlotID) (*e.Slot, error)
-	CreateSlot(slot *e.Slot) (e.SlotID, error)
-	GetAllSlots() ([]*e.Slot, error)
+	GetSlotByID(ctx context.Context, id e.SlotID) (*e.Slot, error)
+	CreateSlot(ctx context.Context, slot *e.Slot) (e.SlotID, error)
+	GetAllSlots(ctx context.Context) ([]*e.Slot, error)
+	UpdateSlotRotation(ctx context.Context, slot *e.Slot) error
 }
 
 type PgSlotRepository struct {
 	DB *sql.DB
 }
 
-func (r *PgSlotRepository) GetSlotByID(id e.SlotID) (*e.Slot, error) {
+func (r *PgSlotRepository) GetSlotByID(ctx context.Context, id e.SlotID) (*e.Slot, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx)
+	defer cancel()
+
+	sql := `SELECT id, description, goal, strategy, holdout_percent FROM slots WHERE id = $1`
+
 	slot := &e.Slot{}
-	err := r.DB.QueryRow("SELECT id, description FROM slots WHERE id = $1", id).Scan(&slot.ID, &slot.Description)
+	err := r.DB.QueryRowContext(ctx, sql, id).
+		Scan(&slot.ID, &slot.Description, &slot.Goal, &slot.Strategy, &slot.HoldoutPercent)
 	if err != nil {
-		return nil, err
+		return nil, repository.WrapError(err)
 	}
 	return slot, nil
 }
 
-func (r *PgSlotRepository) CreateSlot(slot *e.Slot) (e.SlotID, error) {
+func (r *PgSlotRepository) CreateSlot(ctx context.Context, slot *e.Slot) (e.SlotID, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx)
+	defer cancel()
+
 	var id e.SlotID
-	err := r.DB.QueryRow("INSERT INTO slots (description) VALUES ($1) RETURNING id", slot.Description).Scan(&id)
+	err := r.DB.QueryRowContext(ctx, "INSERT INTO slots (description) VALUES ($1) RETURNING id", slot.Description).
+		Scan(&id)
 	if err != nil {
-		return 0, err
+		return 0, repository.WrapError(err)
 	}
 	return id, nil
 }
 
-func (r *PgSlotRepository) GetAllSlots() ([]*e.Slot, error) {
-	sql := `SELECT id, description FROM slots`
-	rows, err := r.DB.Query(sql)
-	if rows.Err() != nil {
-		return nil, rows.Err()
-	}
+func (r *PgSlotRepository) GetAllSlots(ctx context.Context) ([]*e.Slot, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx)
+	defer cancel()
+
+	sql := `SELECT id, description, goal, strategy, holdout_percent FROM slots`
+	rows, err := r.DB.QueryContext(ctx, sql)
 	if err != nil {
-		return nil, err
+		return nil, repository.WrapError(err)
 	}
 	defer rows.Close()
 
 	var slots []*e.Slot
 	for rows.Next() {
 		slot := &e.Slot{}
-		if err := rows.Scan(&slot.ID, &slot.Description); err != nil {
-			return nil, err
+		if err := rows.Scan(&slot.ID, &slot.Description, &slot.Goal, &slot.Strategy, &slot.HoldoutPercent); err != nil {
+			return nil, repository.WrapError(err)
 		}
 		slots = append(slots, slot)
 	}
 
+	if err := rows.Err(); err != nil {
+		return nil, repository.WrapError(err)
+	}
+
 	return slots, nil
 }
+
+// UpdateSlotRotation stores what the bandit optimizes in a slot, how, and the
+// share of its traffic held out. It returns sql.ErrNoRows if the slot does not
+// exist.
+func (r *PgSlotRepository) UpdateSlotRotation(ctx context.Context, slot *e.Slot) error {
+	ctx, cancel := repository.WithQueryTimeout(ctx)
+	defer cancel()
+
+	result, err := r.DB.ExecContext(ctx,
+		"UPDATE slots SET goal = $2, strategy = $3, holdout_percent = $4 WHERE id = $1",
+		slot.ID, slot.Goal, slot.Strategy, slot.HoldoutPercent)
+	if err != nil {
+		return repository.WrapError(err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return repository.WrapError(err)
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
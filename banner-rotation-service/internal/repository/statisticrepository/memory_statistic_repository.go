@@ -0,0 +1,214 @@
+package statisticrepository
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	e "github.com/yuriiwanchev/banner-rotation-service/internal/entities"
+)
+
+var (
+	_ StatisticRepository = (*PgStatisticRepository)(nil)
+	_ StatisticRepository = (*MemStatisticRepository)(nil)
+)
+
+type statisticKey struct {
+	slotID      e.SlotID
+	bannerID    e.BannerID
+	userGroupID e.UserGroupID
+}
+
+// MemStatisticRepository is a thread-safe in-memory StatisticRepository.
+// As with the UPDATE statements of PgStatisticRepository, incrementing a row
+// that was never created is a no-op.
+type MemStatisticRepository struct {
+	stats  map[statisticKey]*e.Statistics
+	daily  map[dailyKey]int
+	nextID int
+	mu     sync.RWMutex
+}
+
+type dailyKey struct {
+	slotID   e.SlotID
+	bannerID e.BannerID
+	day      string
+}
+
+func NewMemStatisticRepository() *MemStatisticRepository {
+	return &MemStatisticRepository{
+		stats:  make(map[statisticKey]*e.Statistics),
+		daily:  make(map[dailyKey]int),
+		nextID: 1,
+	}
+}
+
+func (r *MemStatisticRepository) CreateStartStatisticsForBannerInSlot(_ context.Context,
+	slotID e.SlotID, bannerID e.BannerID, userGroupID []e.UserGroupID,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, groupID := range userGroupID {
+		// As in PgStatisticRepository, a banner re-added to a slot keeps its statistics.
+		key := statisticKey{slotID, bannerID, groupID}
+		if _, exists := r.stats[key]; exists {
+			continue
+		}
+		r.stats[key] = &e.Statistics{
+			ID:          r.nextID,
+			SlotID:      slotID,
+			BannerID:    bannerID,
+			UserGroupID: groupID,
+		}
+		r.nextID++
+	}
+	return nil
+}
+
+func (r *MemStatisticRepository) GetStatistics(_ context.Context, slotID e.SlotID, bannerID e.BannerID,
+	userGroupID e.UserGroupID,
+) (*e.Statistics, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stat, exists := r.stats[statisticKey{slotID, bannerID, userGroupID}]
+	if !exists {
+		return nil, sql.ErrNoRows
+	}
+	statCopy := *stat
+	return &statCopy, nil
+}
+
+func (r *MemStatisticRepository) GetStatisticsForSlotAndBanner(_ context.Context, slotID e.SlotID,
+	bannerID e.BannerID,
+) (*e.Statistics, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var found *e.Statistics
+	for key, stat := range r.stats {
+		if key.slotID == slotID && key.bannerID == bannerID && (found == nil || stat.ID < found.ID) {
+			found = stat
+		}
+	}
+	if found == nil {
+		return nil, sql.ErrNoRows
+	}
+	statCopy := *found
+	return &statCopy, nil
+}
+
+func (r *MemStatisticRepository) GetStatisticsForSlot(_ context.Context, slotID e.SlotID) ([]*e.Statistics, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var stats []*e.Statistics
+	for key, stat := range r.stats {
+		if key.slotID == slotID {
+			statCopy := *stat
+			stats = append(stats, &statCopy)
+		}
+	}
+	return stats, nil
+}
+
+func (r *MemStatisticRepository) UpdateStatistics(_ context.Context, stat *e.Statistics) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if stored, exists := r.stats[statisticKey{stat.SlotID, stat.BannerID, stat.UserGroupID}]; exists {
+		stored.Clicks = stat.Clicks
+		stored.Views = stat.Views
+		stored.Conversions = stat.Conversions
+		stored.Revenue = stat.Revenue
+		stored.RevenueSquares = stat.RevenueSquares
+		stored.ControlViews = stat.ControlViews
+		stored.ControlClicks = stat.ControlClicks
+	}
+	return nil
+}
+
+func (r *MemStatisticRepository) IncrementClick(_ context.Context, slotID e.SlotID, bannerID e.BannerID,
+	userGroupID e.UserGroupID,
+) error {
+	r.incrementClick(slotID, bannerID, userGroupID, 0)
+	return nil
+}
+
+func (r *MemStatisticRepository) IncrementControlClick(_ context.Context, slotID e.SlotID, bannerID e.BannerID,
+	userGroupID e.UserGroupID,
+) error {
+	r.incrementClick(slotID, bannerID, userGroupID, 1)
+	return nil
+}
+
+func (r *MemStatisticRepository) incrementClick(slotID e.SlotID, bannerID e.BannerID, userGroupID e.UserGroupID,
+	control int,
+) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if stat, exists := r.stats[statisticKey{slotID, bannerID, userGroupID}]; exists {
+		stat.Clicks++
+		stat.ControlClicks += control
+	}
+}
+
+func (r *MemStatisticRepository) IncrementView(_ context.Context, slotID e.SlotID, bannerID e.BannerID,
+	userGroupID e.UserGroupID,
+) error {
+	r.incrementView(slotID, bannerID, userGroupID, 0)
+	return nil
+}
+
+func (r *MemStatisticRepository) IncrementControlView(_ context.Context, slotID e.SlotID, bannerID e.BannerID,
+	userGroupID e.UserGroupID,
+) error {
+	r.incrementView(slotID, bannerID, userGroupID, 1)
+	return nil
+}
+
+func (r *MemStatisticRepository) incrementView(slotID e.SlotID, bannerID e.BannerID, userGroupID e.UserGroupID,
+	control int,
+) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if stat, exists := r.stats[statisticKey{slotID, bannerID, userGroupID}]; exists {
+		stat.Views++
+		stat.ControlViews += control
+		r.daily[dailyKey{slotID, bannerID, time.Now().UTC().Format(time.DateOnly)}]++
+	}
+}
+
+func (r *MemStatisticRepository) IncrementConversion(_ context.Context, slotID e.SlotID, bannerID e.BannerID,
+	userGroupID e.UserGroupID, value float64,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if stat, exists := r.stats[statisticKey{slotID, bannerID, userGroupID}]; exists {
+		stat.Conversions++
+		stat.Revenue += value
+		stat.RevenueSquares += value * value
+	}
+	return nil
+}
+
+func (r *MemStatisticRepository) GetDailyImpressionsForSlot(_ context.Context, slotID e.SlotID,
+	day time.Time,
+) (map[e.BannerID]int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	date := day.UTC().Format(time.DateOnly)
+	impressions := make(map[e.BannerID]int)
+	for key, count := range r.daily {
+		if key.slotID == slotID && key.day == date {
+			impressions[key.bannerID] = count
+		}
+	}
+	return impressions, nil
+}
@@ -1,109 +1,270 @@
 package statisticrepository
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"time"
 
 	e "github.com/yuriiwanchev/banner-rotation-service/internal/entities"
+	"github.com/yuriiwanchev/banner-rotation-service/internal/repository"
 )
 
 type StatisticRepository interface {
-	CreateStartStatisticsForBannerInSlot(slotID e.SlotID, bannerID e.BannerID, userGroupID []e.UserGroupID) error
-	GetStatistics(slotID e.SlotID, bannerID e.BannerID, userGroupID e.UserGroupID) (*e.Statistics, error)
-	GetStatisticsForSlotAndBanner(slotID e.SlotID, bannerID e.BannerID) (*e.Statistics, error)
-	UpdateStatistics(stat *e.Statistics) error
-	IncrementClick(slotID e.SlotID, bannerID e.BannerID, userGroupID e.UserGroupID) error
-	IncrementView(slotID e.SlotID, bannerID e.BannerID, userGroupID e.UserGroupID) error
+	CreateStartStatisticsForBannerInSlot(ctx context.Context, slotID e.SlotID, bannerID e.BannerID,
+		userGroupID []e.UserGroupID) error
+	GetStatistics(ctx context.Context, slotID e.SlotID, bannerID e.BannerID,
+		userGroupID e.UserGroupID) (*e.Statistics, error)
+	GetStatisticsForSlotAndBanner(ctx context.Context, slotID e.SlotID, bannerID e.BannerID) (*e.Statistics, error)
+	GetStatisticsForSlot(ctx context.Context, slotID e.SlotID) ([]*e.Statistics, error)
+	UpdateStatistics(ctx context.Context, stat *e.Statistics) error
+	IncrementClick(ctx context.Context, slotID e.SlotID, bannerID e.BannerID, userGroupID e.UserGroupID) error
+	IncrementView(ctx context.Context, slotID e.SlotID, bannerID e.BannerID, userGroupID e.UserGroupID) error
+	IncrementControlClick(ctx context.Context, slotID e.SlotID, bannerID e.BannerID, userGroupID e.UserGroupID) error
+	IncrementControlView(ctx context.Context, slotID e.SlotID, bannerID e.BannerID, userGroupID e.UserGroupID) error
+	IncrementConversion(ctx context.Context, slotID e.SlotID, bannerID e.BannerID, userGroupID e.UserGroupID,
+		value float64) error
+	GetDailyImpressionsForSlot(ctx context.Context, slotID e.SlotID, day time.Time) (map[e.BannerID]int, error)
 }
 
 type PgStatisticRepository struct {
 	DB *sql.DB
 }
 
-func (r *PgStatisticRepository) CreateStartStatisticsForBannerInSlot(
+func (r *PgStatisticRepository) CreateStartStatisticsForBannerInSlot(ctx context.Context,
 	slotID e.SlotID, bannerID e.BannerID, userGroupID []e.UserGroupID,
 ) error {
-	tx, err := r.DB.Begin()
+	ctx, cancel := repository.WithQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := r.DB.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", repository.WrapError(err))
 	}
 
+	// A banner re-added to a slot keeps the statistics it had.
 	sql := `INSERT INTO statistics (slot_id, banner_id, user_group_id, clicks, views)
-			VALUES ($1, $2, $3, $4, $5)`
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (slot_id, banner_id, user_group_id) DO NOTHING`
 
 	for _, groupID := range userGroupID {
-		_, err := tx.Exec(sql, slotID, bannerID, groupID, 0, 0)
+		_, err := tx.ExecContext(ctx, sql, slotID, bannerID, groupID, 0, 0)
 		if err != nil {
 			// В случае ошибки, откатываем транзакцию
 			tx.Rollback()
-			return fmt.Errorf("failed to insert data for user group %v: %w", groupID, err)
+			return fmt.Errorf("failed to insert data for user group %v: %w", groupID, repository.WrapError(err))
 		}
 	}
 
 	// Фиксируем транзакцию
 	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+		return fmt.Errorf("failed to commit transaction: %w", repository.WrapError(err))
 	}
 
 	return nil
 }
 
-func (r *PgStatisticRepository) GetStatistics(slotID e.SlotID, bannerID e.BannerID,
+func (r *PgStatisticRepository) GetStatistics(ctx context.Context, slotID e.SlotID, bannerID e.BannerID,
 	userGroupID e.UserGroupID,
 ) (*e.Statistics, error) {
-	sql := `SELECT id, slot_id, banner_id, user_group_id, clicks, views 
+	ctx, cancel := repository.WithQueryTimeout(ctx)
+	defer cancel()
+
+	sql := `SELECT id, slot_id, banner_id, user_group_id, clicks, views, conversions, revenue, revenue_squares, 
+				control_views, control_clicks 
 			FROM statistics 
 			WHERE slot_id = $1 
 				AND banner_id = $2 
 				AND user_group_id = $3`
 
 	stat := &e.Statistics{}
-	err := r.DB.QueryRow(sql, slotID, bannerID, userGroupID).Scan(&stat.ID, &stat.SlotID, &stat.BannerID,
-		&stat.UserGroupID, &stat.Clicks, &stat.Views)
+	err := r.DB.QueryRowContext(ctx, sql, slotID, bannerID, userGroupID).Scan(&stat.ID, &stat.SlotID, &stat.BannerID,
+		&stat.UserGroupID, &stat.Clicks, &stat.Views, &stat.Conversions, &stat.Revenue, &stat.RevenueSquares,
+		&stat.ControlViews, &stat.ControlClicks)
 	if err != nil {
-		return nil, err
+		return nil, repository.WrapError(err)
 	}
 	return stat, nil
 }
 
-func (r *PgStatisticRepository) GetStatisticsForSlotAndBanner(slotID e.SlotID,
+func (r *PgStatisticRepository) GetStatisticsForSlotAndBanner(ctx context.Context, slotID e.SlotID,
 	bannerID e.BannerID,
 ) (*e.Statistics, error) {
-	sql := `SELECT id, slot_id, banner_id, user_group_id, clicks, views 
+	ctx, cancel := repository.WithQueryTimeout(ctx)
+	defer cancel()
+
+	sql := `SELECT id, slot_id, banner_id, user_group_id, clicks, views, conversions, revenue, revenue_squares, 
+				control_views, control_clicks 
 			FROM statistics 
 			WHERE slot_id = $1 
 				AND banner_id = $2`
 
 	stat := &e.Statistics{}
-	err := r.DB.QueryRow(sql, slotID, bannerID).Scan(&stat.ID, &stat.SlotID, &stat.BannerID,
-		&stat.UserGroupID, &stat.Clicks, &stat.Views)
+	err := r.DB.QueryRowContext(ctx, sql, slotID, bannerID).Scan(&stat.ID, &stat.SlotID, &stat.BannerID,
+		&stat.UserGroupID, &stat.Clicks, &stat.Views, &stat.Conversions, &stat.Revenue, &stat.RevenueSquares,
+		&stat.ControlViews, &stat.ControlClicks)
 	if err != nil {
-		return nil, err
+		return nil, repository.WrapError(err)
 	}
 	return stat, nil
 }
 
-func (r *PgStatisticRepository) UpdateStatistics(stat *e.Statistics) error {
+// GetStatisticsForSlot returns the statistics of every banner and user group of a slot.
+func (r *PgStatisticRepository) GetStatisticsForSlot(ctx context.Context, slotID e.SlotID) ([]*e.Statistics, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx)
+	defer cancel()
+
+	sql := `SELECT id, slot_id, banner_id, user_group_id, clicks, views, conversions, revenue, revenue_squares, 
+				control_views, control_clicks 
+			FROM statistics 
+			WHERE slot_id = $1`
+
+	rows, err := r.DB.QueryContext(ctx, sql, slotID)
+	if err != nil {
+		return nil, repository.WrapError(err)
+	}
+	defer rows.Close()
+
+	var stats []*e.Statistics
+	for rows.Next() {
+		stat := &e.Statistics{}
+		if err := rows.Scan(&stat.ID, &stat.SlotID, &stat.BannerID, &stat.UserGroupID,
+			&stat.Clicks, &stat.Views, &stat.Conversions, &stat.Revenue, &stat.RevenueSquares,
+			&stat.ControlViews, &stat.ControlClicks); err != nil {
+			return nil, fmt.Errorf("failed to scan statistics: %w", repository.WrapError(err))
+		}
+		stats = append(stats, stat)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error occurred during row iteration: %w", repository.WrapError(err))
+	}
+
+	return stats, nil
+}
+
+func (r *PgStatisticRepository) UpdateStatistics(ctx context.Context, stat *e.Statistics) error {
+	ctx, cancel := repository.WithQueryTimeout(ctx)
+	defer cancel()
+
 	sql := `UPDATE statistics 
-			SET clicks = $1, views = $2 
-			WHERE slot_id = $3 AND banner_id = $4 AND user_group_id = $5`
-	_, err := r.DB.Exec(sql,
-		stat.Clicks, stat.Views, stat.SlotID, stat.BannerID, stat.UserGroupID)
-	return err
+			SET clicks = $1, views = $2, conversions = $3, revenue = $4, revenue_squares = $5, 
+				control_views = $6, control_clicks = $7 
+			WHERE slot_id = $8 AND banner_id = $9 AND user_group_id = $10`
+	_, err := r.DB.ExecContext(ctx, sql, stat.Clicks, stat.Views, stat.Conversions, stat.Revenue,
+		stat.RevenueSquares, stat.ControlViews, stat.ControlClicks, stat.SlotID, stat.BannerID, stat.UserGroupID)
+	return repository.WrapError(err)
+}
+
+func (r *PgStatisticRepository) IncrementClick(ctx context.Context, slotID e.SlotID, bannerID e.BannerID,
+	userGroupID e.UserGroupID,
+) error {
+	return r.incrementClick(ctx, slotID, bannerID, userGroupID, 0)
 }
 
-func (r *PgStatisticRepository) IncrementClick(slotID e.SlotID, bannerID e.BannerID, userGroupID e.UserGroupID) error {
+// IncrementControlClick counts a click of the holdout control group, both in
+// clicks and in control clicks.
+func (r *PgStatisticRepository) IncrementControlClick(ctx context.Context, slotID e.SlotID, bannerID e.BannerID,
+	userGroupID e.UserGroupID,
+) error {
+	return r.incrementClick(ctx, slotID, bannerID, userGroupID, 1)
+}
+
+func (r *PgStatisticRepository) incrementClick(ctx context.Context, slotID e.SlotID, bannerID e.BannerID,
+	userGroupID e.UserGroupID, control int,
+) error {
+	ctx, cancel := repository.WithQueryTimeout(ctx)
+	defer cancel()
+
 	sql := `UPDATE statistics 
-			SET clicks = clicks + 1 
+			SET clicks = clicks + 1, control_clicks = control_clicks + $4 
 			WHERE slot_id = $1 AND banner_id = $2 AND user_group_id = $3`
-	_, err := r.DB.Exec(sql, slotID, bannerID, userGroupID)
-	return err
+	_, err := r.DB.ExecContext(ctx, sql, slotID, bannerID, userGroupID, control)
+	return repository.WrapError(err)
 }
 
-func (r *PgStatisticRepository) IncrementView(slotID e.SlotID, bannerID e.BannerID, userGroupID e.UserGroupID) error {
+func (r *PgStatisticRepository) IncrementView(ctx context.Context, slotID e.SlotID, bannerID e.BannerID,
+	userGroupID e.UserGroupID,
+) error {
+	return r.incrementView(ctx, slotID, bannerID, userGroupID, 0)
+}
+
+// IncrementControlView counts a view of the holdout control group, both in
+// views and in control views.
+func (r *PgStatisticRepository) IncrementControlView(ctx context.Context, slotID e.SlotID, bannerID e.BannerID,
+	userGroupID e.UserGroupID,
+) error {
+	return r.incrementView(ctx, slotID, bannerID, userGroupID, 1)
+}
+
+func (r *PgStatisticRepository) incrementView(ctx context.Context, slotID e.SlotID, bannerID e.BannerID,
+	userGroupID e.UserGroupID, control int,
+) error {
+	ctx, cancel := repository.WithQueryTimeout(ctx)
+	defer cancel()
+
+	// The view is also counted towards the daily impressions of the banner, in
+	// the same statement so that both counters stay in step.
+	sql := `WITH viewed AS (
+				UPDATE statistics 
+				SET views = views + 1, control_views = control_views + $4 
+				WHERE slot_id = $1 AND banner_id = $2 AND user_group_id = $3 
+				RETURNING slot_id, banner_id
+			)
+			INSERT INTO daily_impressions (slot_id, banner_id, day, impressions) 
+			SELECT DISTINCT slot_id, banner_id, (now() AT TIME ZONE 'UTC')::date, 1 FROM viewed 
+			ON CONFLICT (slot_id, banner_id, day) 
+			DO UPDATE SET impressions = daily_impressions.impressions + 1`
+	_, err := r.DB.ExecContext(ctx, sql, slotID, bannerID, userGroupID, control)
+	return repository.WrapError(err)
+}
+
+// IncrementConversion counts a conversion and adds its value to the revenue,
+// and its square to the revenue squares.
+func (r *PgStatisticRepository) IncrementConversion(ctx context.Context, slotID e.SlotID, bannerID e.BannerID,
+	userGroupID e.UserGroupID, value float64,
+) error {
+	ctx, cancel := repository.WithQueryTimeout(ctx)
+	defer cancel()
+
 	sql := `UPDATE statistics 
-			SET views = views + 1 
+			SET conversions = conversions + 1, revenue = revenue + $4, revenue_squares = revenue_squares + $4 * $4 
 			WHERE slot_id = $1 AND banner_id = $2 AND user_group_id = $3`
-	_, err := r.DB.Exec(sql, slotID, bannerID, userGroupID)
-	return err
+	_, err := r.DB.ExecContext(ctx, sql, slotID, bannerID, userGroupID, value)
+	return repository.WrapError(err)
+}
+
+// GetDailyImpressionsForSlot returns the views of the banners of a slot on the
+// UTC day containing day, across all user groups.
+func (r *PgStatisticRepository) GetDailyImpressionsForSlot(ctx context.Context, slotID e.SlotID,
+	day time.Time,
+) (map[e.BannerID]int, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx)
+	defer cancel()
+
+	sql := `SELECT banner_id, impressions 
+			FROM daily_impressions 
+			WHERE slot_id = $1 AND day = $2`
+
+	rows, err := r.DB.QueryContext(ctx, sql, slotID, day.UTC().Format(time.DateOnly))
+	if err != nil {
+		return nil, repository.WrapError(err)
+	}
+	defer rows.Close()
+
+	impressions := make(map[e.BannerID]int)
+	for rows.Next() {
+		var bannerID e.BannerID
+		var count int
+		if err := rows.Scan(&bannerID, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan daily impressions: %w", repository.WrapError(err))
+		}
+		impressions[bannerID] = count
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error occurred during row iteration: %w", repository.WrapError(err))
+	}
+
+	return impressions, nil
 }
@@ -0,0 +1,69 @@
+package usergrouprepository
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"sync"
+
+	e "github.com/yuriiwanchev/banner-rotation-service/internal/entities"
+)
+
+var (
+	_ UserGroupRepository = (*PgUserGroupRepository)(nil)
+	_ UserGroupRepository = (*MemUserGroupRepository)(nil)
+)
+
+// MemUserGroupRepository is a thread-safe in-memory UserGroupRepository.
+type MemUserGroupRepository struct {
+	groups map[e.UserGroupID]e.UserGroup
+	nextID e.UserGroupID
+	mu     sync.RWMutex
+}
+
+func NewMemUserGroupRepository() *MemUserGroupRepository {
+	return &MemUserGroupRepository{
+		groups: make(map[e.UserGroupID]e.UserGroup),
+		nextID: 1,
+	}
+}
+
+func (r *MemUserGroupRepository) GetUserGroupByID(_ context.Context, id e.UserGroupID) (*e.UserGroup, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	group, exists := r.groups[id]
+	if !exists {
+		return nil, sql.ErrNoRows
+	}
+	return &group, nil
+}
+
+// CreateUserGroup stores the group under group.ID if it is set, or under the next free ID.
+func (r *MemUserGroupRepository) CreateUserGroup(_ context.Context, group *e.UserGroup) (e.UserGroupID, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := group.ID
+	if id == 0 {
+		id = r.nextID
+	}
+	if id >= r.nextID {
+		r.nextID = id + 1
+	}
+
+	r.groups[id] = e.UserGroup{ID: id, Description: group.Description}
+	return id, nil
+}
+
+func (r *MemUserGroupRepository) GetAllUserGroupsIDs(_ context.Context) ([]e.UserGroupID, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ids := make([]e.UserGroupID, 0, len(r.groups))
+	for id := range r.groups {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids, nil
+}
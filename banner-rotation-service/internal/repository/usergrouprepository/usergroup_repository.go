@@ -1,45 +1,58 @@
 package usergrouprepository
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 
 	e "github.com/yuriiwanchev/banner-rotation-service/internal/entities"
+	"github.com/yuriiwanchev/banner-rotation-service/internal/repository"
 )
 
 type UserGroupRepository interface {
-	GetUserGroupByID(id e.UserGroupID) (*e.UserGroup, error)
-	CreateUserGroup(group *e.UserGroup) (e.UserGroupID, error)
-	GetAllUserGroupsIds() ([]e.UserGroupID, error)
+	GetUserGroupByID(ctx context.Context, id e.UserGroupID) (*e.UserGroup, error)
+	CreateUserGroup(ctx context.Context, group *e.UserGroup) (e.UserGroupID, error)
+	GetAllUserGroupsIDs(ctx context.Context) ([]e.UserGroupID, error)
 }
 
 type PgUserGroupRepository struct {
 	DB *sql.DB
 }
 
-func (r *PgUserGroupRepository) GetUserGroupByID(id e.UserGroupID) (*e.UserGroup, error) {
+func (r *PgUserGroupRepository) GetUserGroupByID(ctx context.Context, id e.UserGroupID) (*e.UserGroup, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx)
+	defer cancel()
+
 	group := &e.UserGroup{}
-	err := r.DB.QueryRow("SELECT id, description FROM user_groups WHERE id = $1", id).Scan(&group.ID, &group.Description)
+	err := r.DB.QueryRowContext(ctx, "SELECT id, description FROM user_groups WHERE id = $1", id).
+		Scan(&group.ID, &group.Description)
 	if err != nil {
-		return nil, err
+		return nil, repository.WrapError(err)
 	}
 	return group, nil
 }
 
-func (r *PgUserGroupRepository) CreateUserGroup(group *e.UserGroup) (e.UserGroupID, error) {
+func (r *PgUserGroupRepository) CreateUserGroup(ctx context.Context, group *e.UserGroup) (e.UserGroupID, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx)
+	defer cancel()
+
 	var id e.UserGroupID
-	err := r.DB.QueryRow("INSERT INTO user_groups (description) VALUES ($1) RETURNING id", group.Description).Scan(&id)
+	err := r.DB.QueryRowContext(ctx, "INSERT INTO user_groups (description) VALUES ($1) RETURNING id",
+		group.Description).Scan(&id)
 	if err != nil {
-		return 0, err
+		return 0, repository.WrapError(err)
 	}
 	return id, nil
 }
 
-func (r *PgUserGroupRepository) GetAllUserGroupsIDs() ([]e.UserGroupID, error) {
+func (r *PgUserGroupRepository) GetAllUserGroupsIDs(ctx context.Context) ([]e.UserGroupID, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx)
+	defer cancel()
+
 	sql := `SELECT id FROM user_groups`
-	rows, err := r.DB.Query(sql)
+	rows, err := r.DB.QueryContext(ctx, sql)
 	if err != nil {
-		return nil, err
+		return nil, repository.WrapError(err)
 	}
 	defer rows.Close()
 
@@ -48,13 +61,13 @@ func (r *PgUserGroupRepository) GetAllUserGroupsIDs() ([]e.UserGroupID, error) {
 	for rows.Next() {
 		var id e.UserGroupID
 		if err := rows.Scan(&id); err != nil {
-			return nil, fmt.Errorf("failed to scan id: %w", err)
+			return nil, fmt.Errorf("failed to scan id: %w", repository.WrapError(err))
 		}
 		ids = append(ids, id)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error occurred during row iteration: %w", err)
+		return nil, fmt.Errorf("error occurred during row iteration: %w", repository.WrapError(err))
 	}
 
 	return ids, nil
@@ -0,0 +1,221 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// The methods in this file need an admin API key.
+
+type APIKeyID int
+
+type Banner struct {
+	ID          BannerID  `json:"id"`
+	Description string    `json:"description"`
+	Creative    *Creative `json:"creative,omitempty"`
+}
+
+// Slot is a slot with its rotation settings. Goal is one of "ctr",
+// "conversions" and "revenue", Strategy one of "ucb1" and "ucb-v".
+type Slot struct {
+	ID             SlotID `json:"id"`
+	Description    string `json:"description"`
+	Goal           string `json:"goal"`
+	Strategy       string `json:"strategy"`
+	HoldoutPercent int    `json:"holdoutPercent"`
+}
+
+// SlotSettings replace every rotation setting of a slot: an empty Goal is
+// "ctr", an empty Strategy "ucb1" and a zero HoldoutPercent disables the
+// control group.
+type SlotSettings struct {
+	Goal           string `json:"goal,omitempty"`
+	Strategy       string `json:"strategy,omitempty"`
+	HoldoutPercent int    `json:"holdoutPercent,omitempty"`
+}
+
+// SlotBanner is the schedule of a banner in a slot. Status is "active" or
+// "paused"; zero daily impressions mean no minimum or no cap.
+type SlotBanner struct {
+	SlotID              SlotID     `json:"slotId"`
+	BannerID            BannerID   `json:"bannerId"`
+	StartsAt            *time.Time `json:"startsAt,omitempty"`
+	EndsAt              *time.Time `json:"endsAt,omitempty"`
+	Status              string     `json:"status"`
+	MinDailyImpressions int        `json:"minDailyImpressions"`
+	MaxDailyImpressions int        `json:"maxDailyImpressions"`
+}
+
+// SlotBannerSchedule replaces the schedule of a banner in a slot. Nil dates
+// leave the flight unbounded on that side and an empty Status means active.
+type SlotBannerSchedule struct {
+	StartsAt            *time.Time `json:"startsAt,omitempty"`
+	EndsAt              *time.Time `json:"endsAt,omitempty"`
+	Status              string     `json:"status,omitempty"`
+	MinDailyImpressions int        `json:"minDailyImpressions,omitempty"`
+	MaxDailyImpressions int        `json:"maxDailyImpressions,omitempty"`
+}
+
+// BannerDelivery is the progress of a banner towards its daily impression
+// goals. RemainingImpressions is nil for banners without a cap.
+type BannerDelivery struct {
+	BannerID             BannerID `json:"bannerId"`
+	Day                  string   `json:"day"`
+	MinDailyImpressions  int      `json:"minDailyImpressions"`
+	MaxDailyImpressions  int      `json:"maxDailyImpressions"`
+	Impressions          int      `json:"impressions"`
+	MissingImpressions   int      `json:"missingImpressions"`
+	RemainingImpressions *int     `json:"remainingImpressions,omitempty"`
+}
+
+type TrafficStats struct {
+	Views  int     `json:"views"`
+	Clicks int     `json:"clicks"`
+	CTR    float64 `json:"ctr"`
+}
+
+// LiftReport compares the bandit with the control group of a slot. Lift and
+// ZScore are nil until the control group has clicks.
+type LiftReport struct {
+	SlotID         SlotID       `json:"slotId"`
+	HoldoutPercent int          `json:"holdoutPercent"`
+	Bandit         TrafficStats `json:"bandit"`
+	Control        TrafficStats `json:"control"`
+	Lift           *float64     `json:"lift,omitempty"`
+	ZScore         *float64     `json:"zScore,omitempty"`
+}
+
+// APIKey is an issued key without its secret. Role is "serving" or "admin".
+type APIKey struct {
+	ID        APIKeyID   `json:"id"`
+	Name      string     `json:"name"`
+	Role      string     `json:"role"`
+	CreatedAt time.Time  `json:"createdAt"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+}
+
+// CreatedAPIKey is a newly issued key. Key is only ever returned here.
+type CreatedAPIKey struct {
+	ID   APIKeyID `json:"id"`
+	Name string   `json:"name"`
+	Role string   `json:"role"`
+	Key  string   `json:"key"`
+}
+
+type ReloadResult struct {
+	Slots   int `json:"slots"`
+	Banners int `json:"banners"`
+}
+
+// CreateBanner creates a banner; creative may be nil.
+func (c *Client) CreateBanner(ctx context.Context, description string, creative *Creative) (*Banner, error) {
+	body := struct {
+		Description string    `json:"description"`
+		Creative    *Creative `json:"creative,omitempty"`
+	}{description, creative}
+	banner := &Banner{}
+	if err := c.do(ctx, http.MethodPost, "/v1/banners", body, banner); err != nil {
+		return nil, err
+	}
+	return banner, nil
+}
+
+func (c *Client) GetBanner(ctx context.Context, bannerID BannerID) (*Banner, error) {
+	banner := &Banner{}
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/v1/banners/%d", bannerID), nil, banner); err != nil {
+		return nil, err
+	}
+	return banner, nil
+}
+
+func (c *Client) GetSlot(ctx context.Context, slotID SlotID) (*Slot, error) {
+	slot := &Slot{}
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/v1/slots/%d", slotID), nil, slot); err != nil {
+		return nil, err
+	}
+	return slot, nil
+}
+
+// UpdateSlot replaces the rotation settings of a slot.
+func (c *Client) UpdateSlot(ctx context.Context, slotID SlotID, settings SlotSettings) (*Slot, error) {
+	slot := &Slot{}
+	if err := c.do(ctx, http.MethodPut, fmt.Sprintf("/v1/slots/%d", slotID), settings, slot); err != nil {
+		return nil, err
+	}
+	return slot, nil
+}
+
+func (c *Client) GetSlotLift(ctx context.Context, slotID SlotID) (*LiftReport, error) {
+	report := &LiftReport{}
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/v1/slots/%d/lift", slotID), nil, report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func (c *Client) ListSlotBanners(ctx context.Context, slotID SlotID) ([]SlotBanner, error) {
+	var banners []SlotBanner
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/v1/slots/%d/banners", slotID), nil, &banners); err != nil {
+		return nil, err
+	}
+	return banners, nil
+}
+
+// UpdateSlotBanner replaces the schedule of a banner in a slot.
+func (c *Client) UpdateSlotBanner(ctx context.Context, slotID SlotID, bannerID BannerID,
+	schedule SlotBannerSchedule,
+) (*SlotBanner, error) {
+	banner := &SlotBanner{}
+	path := fmt.Sprintf("/v1/slots/%d/banners/%d", slotID, bannerID)
+	if err := c.do(ctx, http.MethodPut, path, schedule, banner); err != nil {
+		return nil, err
+	}
+	return banner, nil
+}
+
+// GetSlotDelivery reports today's impressions of the banners of a slot
+// against their daily goals.
+func (c *Client) GetSlotDelivery(ctx context.Context, slotID SlotID) ([]BannerDelivery, error) {
+	var delivery []BannerDelivery
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/v1/slots/%d/delivery", slotID), nil, &delivery); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// CreateAPIKey issues a key with the role "serving" or "admin".
+func (c *Client) CreateAPIKey(ctx context.Context, name, role string) (*CreatedAPIKey, error) {
+	body := struct {
+		Name string `json:"name"`
+		Role string `json:"role"`
+	}{name, role}
+	key := &CreatedAPIKey{}
+	if err := c.do(ctx, http.MethodPost, "/v1/admin/api-keys", body, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func (c *Client) ListAPIKeys(ctx context.Context) ([]APIKey, error) {
+	var keys []APIKey
+	if err := c.do(ctx, http.MethodGet, "/v1/admin/api-keys", nil, &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (c *Client) RevokeAPIKey(ctx context.Context, keyID APIKeyID) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/v1/admin/api-keys/%d", keyID), nil, nil)
+}
+
+// Reload reloads the slots, banners and statistics of the service from the
+// database.
+func (c *Client) Reload(ctx context.Context) (*ReloadResult, error) {
+	result := &ReloadResult{}
+	if err := c.do(ctx, http.MethodPost, "/admin/reload", nil, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,215 @@
+// Package client is a typed Go client for the banner rotation service HTTP API
+// described by /openapi.json.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type (
+	SlotID      int
+	BannerID    int
+	UserGroupID int
+)
+
+// Creative is what is needed to render a banner.
+type Creative struct {
+	ImageURL  string `json:"imageUrl"`
+	AltText   string `json:"altText"`
+	TargetURL string `json:"targetUrl"`
+	Width     int    `json:"width"`
+	Height    int    `json:"height"`
+	Format    string `json:"format"`
+}
+
+type SelectBannerResponse struct {
+	BannerID BannerID `json:"bannerId"`
+	// Creative is nil for banners created without one.
+	Creative *Creative `json:"creative,omitempty"`
+	// ImpressionID identifies this showing of the banner. It confirms the view
+	// in view confirmation mode and attributes clicks on it.
+	ImpressionID string `json:"impressionId,omitempty"`
+	// Control is set if the banner was picked for the holdout control group.
+	Control bool `json:"control,omitempty"`
+}
+
+type BuildInfo struct {
+	Release   string `json:"release"`
+	BuildDate string `json:"buildDate"`
+	GitHash   string `json:"gitHash"`
+}
+
+// APIError is returned for every non-2xx response of the service.
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("banner rotation service: %d %s", e.StatusCode, e.Message)
+}
+
+type Client struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+}
+
+type Option func(*Client)
+
+// WithHTTPClient replaces the default http.Client with a 10 second timeout.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithAPIKey authenticates every request with an API key or a JWT.
+func WithAPIKey(apiKey string) Option {
+	return func(c *Client) {
+		c.apiKey = apiKey
+	}
+}
+
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *Client) AddBanner(ctx context.Context, slotID SlotID, bannerID BannerID) error {
+	body := struct {
+		BannerID BannerID `json:"bannerId"`
+	}{bannerID}
+	return c.do(ctx, http.MethodPost, fmt.Sprintf("/v1/slots/%d/banners", slotID), body, nil)
+}
+
+func (c *Client) RemoveBanner(ctx context.Context, slotID SlotID, bannerID BannerID) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/v1/slots/%d/banners/%d", slotID, bannerID), nil, nil)
+}
+
+func (c *Client) RecordClick(ctx context.Context, slotID SlotID, bannerID BannerID, userGroupID UserGroupID) error {
+	return c.RecordClickForImpression(ctx, slotID, bannerID, userGroupID, "")
+}
+
+// RecordClickForImpression records a click on the selection with the given
+// impression ID, so that clicks on control group selections are told apart.
+func (c *Client) RecordClickForImpression(ctx context.Context, slotID SlotID, bannerID BannerID,
+	userGroupID UserGroupID, impressionID string,
+) error {
+	body := struct {
+		UserGroupID  UserGroupID `json:"userGroupId"`
+		ImpressionID string      `json:"impressionId,omitempty"`
+	}{userGroupID, impressionID}
+	return c.do(ctx, http.MethodPost, fmt.Sprintf("/v1/slots/%d/banners/%d/clicks", slotID, bannerID), body, nil)
+}
+
+// RecordView confirms the view of a selection when the service counts views
+// only once confirmed.
+func (c *Client) RecordView(ctx context.Context, impressionID string) error {
+	return c.do(ctx, http.MethodPost, "/v1/impressions/"+url.PathEscape(impressionID)+"/views", nil, nil)
+}
+
+// RecordConversion records a conversion of a banner; value is its monetary
+// value, or 0 if it has none.
+func (c *Client) RecordConversion(ctx context.Context, slotID SlotID, bannerID BannerID, userGroupID UserGroupID,
+	value float64,
+) error {
+	body := struct {
+		UserGroupID UserGroupID `json:"userGroupId"`
+		Value       float64     `json:"value,omitempty"`
+	}{userGroupID, value}
+	return c.do(ctx, http.MethodPost, fmt.Sprintf("/v1/slots/%d/banners/%d/conversions", slotID, bannerID), body, nil)
+}
+
+func (c *Client) SelectBanner(ctx context.Context, slotID SlotID,
+	userGroupID UserGroupID,
+) (*SelectBannerResponse, error) {
+	return c.SelectBannerForUser(ctx, slotID, userGroupID, "")
+}
+
+// SelectBannerForUser selects a banner for an identified user, so that the
+// service can cap how often the user is shown the same banner.
+func (c *Client) SelectBannerForUser(ctx context.Context, slotID SlotID, userGroupID UserGroupID,
+	userID string,
+) (*SelectBannerResponse, error) {
+	body := struct {
+		UserGroupID UserGroupID `json:"userGroupId"`
+		UserID      string      `json:"userId,omitempty"`
+	}{userGroupID, userID}
+	response := &SelectBannerResponse{}
+	if err := c.do(ctx, http.MethodPost, fmt.Sprintf("/v1/slots/%d/selections", slotID), body, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (c *Client) Version(ctx context.Context) (*BuildInfo, error) {
+	info := &BuildInfo{}
+	if err := c.do(ctx, http.MethodGet, "/version", nil, info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		apiErr := &APIError{StatusCode: resp.StatusCode}
+		var payload struct {
+			Error string `json:"error"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&payload); err == nil {
+			apiErr.Message = payload.Error
+		} else {
+			apiErr.Message = http.StatusText(resp.StatusCode)
+		}
+		return apiErr
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSelectBanner(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/slots/3/selections" {
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		var body map[string]int
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body["userGroupId"] != 2 {
+			t.Errorf("Unexpected body %v (%v)", body, err)
+		}
+		w.Write([]byte(`{"bannerId":7,"creative":{"imageUrl":"https://cdn/7.png"},"impressionId":"abc","control":true}`))
+	}))
+	defer server.Close()
+
+	response, err := New(server.URL).SelectBanner(context.Background(), 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.BannerID != 7 || response.Creative == nil || response.Creative.ImageURL != "https://cdn/7.png" ||
+		response.ImpressionID != "abc" || !response.Control {
+		t.Errorf("Unexpected response %+v", response)
+	}
+}
+
+func TestRecordViewAndClick(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		requests = append(requests, r.Method+" "+r.URL.EscapedPath()+" "+fmt.Sprint(body["impressionId"]))
+	}))
+	defer server.Close()
+
+	c := New(server.URL)
+	if err := c.RecordView(context.Background(), "a/b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.RecordClickForImpression(context.Background(), 3, 7, 2, "abc"); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"POST /v1/impressions/a%2Fb/views <nil>", "POST /v1/slots/3/banners/7/clicks abc"}
+	if fmt.Sprint(requests) != fmt.Sprint(expected) {
+		t.Errorf("Expected requests %v, got %v", expected, requests)
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":"No banner available for the given slot and user group"}`))
+	}))
+	defer server.Close()
+
+	_, err := New(server.URL).SelectBanner(context.Background(), 1, 1)
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Expected APIError, got %v", err)
+	}
+	if apiErr.StatusCode != http.StatusNotFound || apiErr.Message == "" {
+		t.Errorf("Unexpected error %+v", apiErr)
+	}
+}
+
+func TestAdminRequests(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		requests = append(requests, fmt.Sprint(r.Method, " ", r.URL.Path, " ", body))
+		switch r.URL.Path {
+		case "/v1/slots/3":
+			w.Write([]byte(`{"id":3,"goal":"revenue","strategy":"ucb-v","holdoutPercent":10}`))
+		case "/v1/slots/3/banners":
+			w.Write([]byte(`[{"slotId":3,"bannerId":7,"status":"paused"}]`))
+		}
+	}))
+	defer server.Close()
+
+	c := New(server.URL, WithAPIKey("admin-key"))
+	settings := SlotSettings{Goal: "revenue", Strategy: "ucb-v", HoldoutPercent: 10}
+	slot, err := c.UpdateSlot(context.Background(), 3, settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slot.Goal != "revenue" || slot.Strategy != "ucb-v" || slot.HoldoutPercent != 10 {
+		t.Errorf("Unexpected slot %+v", slot)
+	}
+	banners, err := c.ListSlotBanners(context.Background(), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(banners) != 1 || banners[0].BannerID != 7 || banners[0].Status != "paused" {
+		t.Errorf("Unexpected banners %+v", banners)
+	}
+	if err := c.RevokeAPIKey(context.Background(), 5); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"PUT /v1/slots/3 map[goal:revenue holdoutPercent:10 strategy:ucb-v]",
+		"GET /v1/slots/3/banners map[]",
+		"DELETE /v1/admin/api-keys/5 map[]",
+	}
+	if fmt.Sprint(requests) != fmt.Sprint(expected) {
+		t.Errorf("Expected requests %v, got %v", expected, requests)
+	}
+}
@@ -0,0 +1,315 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: banner_rotation.proto
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	BannerRotation_SelectBanner_FullMethodName = "/bannerrotation.v1.BannerRotation/SelectBanner"
+	BannerRotation_RecordView_FullMethodName   = "/bannerrotation.v1.BannerRotation/RecordView"
+	BannerRotation_RecordClick_FullMethodName  = "/bannerrotation.v1.BannerRotation/RecordClick"
+	BannerRotation_RecordClicks_FullMethodName = "/bannerrotation.v1.BannerRotation/RecordClicks"
+	BannerRotation_AddBanner_FullMethodName    = "/bannerrotation.v1.BannerRotation/AddBanner"
+	BannerRotation_RemoveBanner_FullMethodName = "/bannerrotation.v1.BannerRotation/RemoveBanner"
+)
+
+// BannerRotationClient is the client API for BannerRotation service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// BannerRotation exposes the same operations as the HTTP API.
+type BannerRotationClient interface {
+	SelectBanner(ctx context.Context, in *SelectBannerRequest, opts ...grpc.CallOption) (*SelectBannerResponse, error)
+	// RecordView confirms the view of a selected banner when views are counted
+	// only once confirmed.
+	RecordView(ctx context.Context, in *RecordViewRequest, opts ...grpc.CallOption) (*RecordViewResponse, error)
+	RecordClick(ctx context.Context, in *RecordClickRequest, opts ...grpc.CallOption) (*RecordClickResponse, error)
+	// RecordClicks ingests a stream of clicks and reports how many were applied.
+	RecordClicks(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[RecordClickRequest, RecordClicksResponse], error)
+	AddBanner(ctx context.Context, in *AddBannerRequest, opts ...grpc.CallOption) (*AddBannerResponse, error)
+	RemoveBanner(ctx context.Context, in *RemoveBannerRequest, opts ...grpc.CallOption) (*RemoveBannerResponse, error)
+}
+
+type bannerRotationClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewBannerRotationClient(cc grpc.ClientConnInterface) BannerRotationClient {
+	return &bannerRotationClient{cc}
+}
+
+func (c *bannerRotationClient) SelectBanner(ctx context.Context, in *SelectBannerRequest, opts ...grpc.CallOption) (*SelectBannerResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SelectBannerResponse)
+	err := c.cc.Invoke(ctx, BannerRotation_SelectBanner_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bannerRotationClient) RecordView(ctx context.Context, in *RecordViewRequest, opts ...grpc.CallOption) (*RecordViewResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RecordViewResponse)
+	err := c.cc.Invoke(ctx, BannerRotation_RecordView_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bannerRotationClient) RecordClick(ctx context.Context, in *RecordClickRequest, opts ...grpc.CallOption) (*RecordClickResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RecordClickResponse)
+	err := c.cc.Invoke(ctx, BannerRotation_RecordClick_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bannerRotationClient) RecordClicks(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[RecordClickRequest, RecordClicksResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &BannerRotation_ServiceDesc.Streams[0], BannerRotation_RecordClicks_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[RecordClickRequest, RecordClicksResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type BannerRotation_RecordClicksClient = grpc.ClientStreamingClient[RecordClickRequest, RecordClicksResponse]
+
+func (c *bannerRotationClient) AddBanner(ctx context.Context, in *AddBannerRequest, opts ...grpc.CallOption) (*AddBannerResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AddBannerResponse)
+	err := c.cc.Invoke(ctx, BannerRotation_AddBanner_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bannerRotationClient) RemoveBanner(ctx context.Context, in *RemoveBannerRequest, opts ...grpc.CallOption) (*RemoveBannerResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RemoveBannerResponse)
+	err := c.cc.Invoke(ctx, BannerRotation_RemoveBanner_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// BannerRotationServer is the server API for BannerRotation service.
+// All implementations must embed UnimplementedBannerRotationServer
+// for forward compatibility.
+//
+// BannerRotation exposes the same operations as the HTTP API.
+type BannerRotationServer interface {
+	SelectBanner(context.Context, *SelectBannerRequest) (*SelectBannerResponse, error)
+	// RecordView confirms the view of a selected banner when views are counted
+	// only once confirmed.
+	RecordView(context.Context, *RecordViewRequest) (*RecordViewResponse, error)
+	RecordClick(context.Context, *RecordClickRequest) (*RecordClickResponse, error)
+	// RecordClicks ingests a stream of clicks and reports how many were applied.
+	RecordClicks(grpc.ClientStreamingServer[RecordClickRequest, RecordClicksResponse]) error
+	AddBanner(context.Context, *AddBannerRequest) (*AddBannerResponse, error)
+	RemoveBanner(context.Context, *RemoveBannerRequest) (*RemoveBannerResponse, error)
+	mustEmbedUnimplementedBannerRotationServer()
+}
+
+// UnimplementedBannerRotationServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedBannerRotationServer struct{}
+
+func (UnimplementedBannerRotationServer) SelectBanner(context.Context, *SelectBannerRequest) (*SelectBannerResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SelectBanner not implemented")
+}
+func (UnimplementedBannerRotationServer) RecordView(context.Context, *RecordViewRequest) (*RecordViewResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RecordView not implemented")
+}
+func (UnimplementedBannerRotationServer) RecordClick(context.Context, *RecordClickRequest) (*RecordClickResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RecordClick not implemented")
+}
+func (UnimplementedBannerRotationServer) RecordClicks(grpc.ClientStreamingServer[RecordClickRequest, RecordClicksResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method RecordClicks not implemented")
+}
+func (UnimplementedBannerRotationServer) AddBanner(context.Context, *AddBannerRequest) (*AddBannerResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddBanner not implemented")
+}
+func (UnimplementedBannerRotationServer) RemoveBanner(context.Context, *RemoveBannerRequest) (*RemoveBannerResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveBanner not implemented")
+}
+func (UnimplementedBannerRotationServer) mustEmbedUnimplementedBannerRotationServer() {}
+func (UnimplementedBannerRotationServer) testEmbeddedByValue()                        {}
+
+// UnsafeBannerRotationServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to BannerRotationServer will
+// result in compilation errors.
+type UnsafeBannerRotationServer interface {
+	mustEmbedUnimplementedBannerRotationServer()
+}
+
+func RegisterBannerRotationServer(s grpc.ServiceRegistrar, srv BannerRotationServer) {
+	// If the following call pancis, it indicates UnimplementedBannerRotationServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&BannerRotation_ServiceDesc, srv)
+}
+
+func _BannerRotation_SelectBanner_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SelectBannerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BannerRotationServer).SelectBanner(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BannerRotation_SelectBanner_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BannerRotationServer).SelectBanner(ctx, req.(*SelectBannerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BannerRotation_RecordView_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RecordViewRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BannerRotationServer).RecordView(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BannerRotation_RecordView_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BannerRotationServer).RecordView(ctx, req.(*RecordViewRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BannerRotation_RecordClick_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RecordClickRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BannerRotationServer).RecordClick(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BannerRotation_RecordClick_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BannerRotationServer).RecordClick(ctx, req.(*RecordClickRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BannerRotation_RecordClicks_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(BannerRotationServer).RecordClicks(&grpc.GenericServerStream[RecordClickRequest, RecordClicksResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type BannerRotation_RecordClicksServer = grpc.ClientStreamingServer[RecordClickRequest, RecordClicksResponse]
+
+func _BannerRotation_AddBanner_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddBannerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BannerRotationServer).AddBanner(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BannerRotation_AddBanner_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BannerRotationServer).AddBanner(ctx, req.(*AddBannerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BannerRotation_RemoveBanner_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemoveBannerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BannerRotationServer).RemoveBanner(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BannerRotation_RemoveBanner_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BannerRotationServer).RemoveBanner(ctx, req.(*RemoveBannerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// BannerRotation_ServiceDesc is the grpc.ServiceDesc for BannerRotation service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var BannerRotation_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "bannerrotation.v1.BannerRotation",
+	HandlerType: (*BannerRotationServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "SelectBanner",
+			Handler:    _BannerRotation_SelectBanner_Handler,
+		},
+		{
+			MethodName: "RecordView",
+			Handler:    _BannerRotation_RecordView_Handler,
+		},
+		{
+			MethodName: "RecordClick",
+			Handler:    _BannerRotation_RecordClick_Handler,
+		},
+		{
+			MethodName: "AddBanner",
+			Handler:    _BannerRotation_AddBanner_Handler,
+		},
+		{
+			MethodName: "RemoveBanner",
+			Handler:    _BannerRotation_RemoveBanner_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "RecordClicks",
+			Handler:       _BannerRotation_RecordClicks_Handler,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "banner_rotation.proto",
+}
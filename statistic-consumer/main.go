@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 	"os"
@@ -9,6 +10,18 @@ import (
 	"github.com/segmentio/kafka-go"
 )
 
+// event is the part of a banner rotation event the consumer looks at. Other
+// fields, such as the user ID, are deliberately not decoded, so that they never
+// end up in the logs.
+type event struct {
+	Type        string  `json:"type"`
+	SlotID      int     `json:"slotId"`
+	BannerID    int     `json:"bannerId"`
+	UserGroupID int     `json:"userGroupId"`
+	Value       float64 `json:"value,omitempty"`
+	Control     bool    `json:"control,omitempty"`
+}
+
 func main() {
 	kafkaBrokers := os.Getenv("KAFKA_BROKERS")
 	kafkaTopic := os.Getenv("KAFKA_TOPIC")
@@ -28,6 +41,24 @@ func main() {
 		if err != nil {
 			log.Fatal(err)
 		}
-		log.Printf("Message received: %s\n", string(msg.Value))
+
+		var ev event
+		if err := json.Unmarshal(msg.Value, &ev); err != nil {
+			log.Printf("Malformed message at offset %d: %v\n", msg.Offset, err)
+			continue
+		}
+
+		switch ev.Type {
+		case "Heartbeat":
+			// Heartbeats of instances in cluster mode share the topic so that
+			// they measure its lag; they carry no statistics.
+			continue
+		case "Render":
+			// A render confirms that an already counted view was displayed, so
+			// it must not be counted as another view.
+			log.Printf("Render received: %+v\n", ev)
+		default:
+			log.Printf("Message received: %+v\n", ev)
+		}
 	}
 }